@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DocumentResponse is a certificate or document required by a condition of an applicable measure
+type DocumentResponse struct {
+	Certificate            string   `json:"certificate"` // Certificate type and code, e.g. "Y900"
+	Description            string   `json:"description"`
+	GoodsCode              string   `json:"goods_code"` // Goods code the measure is defined on, may be an ancestor of the requested code
+	MeasureType            string   `json:"measure_type"`
+	MeasureTypeDescription string   `json:"measure_type_description"`
+	GeographicalArea       string   `json:"geographical_area"`
+	AdditionalCode         string   `json:"additional_code,omitempty"`
+	ConditionCode          string   `json:"condition_code"`
+	SequenceNumber         int      `json:"sequence_number"`
+	ActionCode             string   `json:"action_code"`
+	DutyAmount             *float64 `json:"duty_amount,omitempty"`
+	Unit                   string   `json:"unit,omitempty"`
+}
+
+// DocumentsResponse lists the documents that may have to be presented for a goods code and origin
+type DocumentsResponse struct {
+	GoodsCode string             `json:"goods_code"`
+	Origin    string             `json:"origin"`
+	Language  string             `json:"language"`
+	AsOf      string             `json:"as_of"`
+	Documents []DocumentResponse `json:"documents"`
+}
+
+// handleGetDocuments serves GET /documents/{goods_code}
+func (s *Server) handleGetDocuments(w http.ResponseWriter, r *http.Request) {
+	goodsCode, err := parseGoodsCode(r.PathValue("goods_code"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	origin := strings.ToUpper(r.URL.Query().Get("origin"))
+	if origin == "" {
+		writeError(w, http.StatusBadRequest, "origin is required")
+		return
+	}
+
+	language, err := parseLanguage(r.URL.Query().Get("lang"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	documents, err := s.findDocuments(r.Context(), goodsCode, origin, language, asOf)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("goods code %s not valid on %s", goodsCode, formatDate(asOf)))
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching documents of goods code %s: %v", goodsCode, err)
+		writeError(w, http.StatusInternalServerError, "failed to fetch documents")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, DocumentsResponse{
+		GoodsCode: goodsCode,
+		Origin:    origin,
+		Language:  language,
+		AsOf:      formatDate(asOf),
+		Documents: documents,
+	})
+}
+
+// findDocuments loads the certificates required by conditions of the measures applicable to the goods code
+// and origin on the given date, with the description of the certificate period valid on that date in the given
+// language.
+// Conditions without a certificate, e.g. entry prices, are not documents and are left out.
+func (s *Server) findDocuments(ctx context.Context, goodsCode string, origin string, language string, asOf time.Time) ([]DocumentResponse, error) {
+	hierarchyPath, err := s.findHierarchyPath(ctx, goodsCode, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT mc.certificate_type || mc.certificate_code, COALESCE(cd.description, ''),
+			m.goods_code, m.measure_type_code, m.measure_type_description, m.geographical_area_code, m.additional_code,
+			mc.condition_code, mc.sequence_number, mc.action_code, mc.duty_amount, mc.unit
+		FROM (
+			SELECT m.id, m.goods_code, m.measure_type_code, mt.description AS measure_type_description,
+				m.geographical_area_code, m.additional_code, ancestors.level
+	`+applicableMeasuresFrom+measureOriginCondition+`
+		) m
+		JOIN measure_conditions mc ON mc.measure_id = m.id
+		LEFT JOIN LATERAL (
+			SELECT id FROM certificates
+			WHERE certificate_type = mc.certificate_type AND code = mc.certificate_code
+			AND start_date <= $2 AND (end_date IS NULL OR end_date >= $2)
+			ORDER BY start_date DESC
+			LIMIT 1
+		) c ON true
+		LEFT JOIN certificate_descriptions cd ON cd.certificate_id = c.id AND cd.language = $4
+		WHERE mc.certificate_code <> ''
+		ORDER BY m.level DESC, m.goods_code DESC, m.measure_type_code, mc.condition_code, mc.sequence_number
+	`, hierarchyPath, asOf, origin, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measure conditions: %v", err)
+	}
+	defer rows.Close()
+
+	documents := []DocumentResponse{}
+	for rows.Next() {
+		var document DocumentResponse
+		if err := rows.Scan(
+			&document.Certificate,
+			&document.Description,
+			&document.GoodsCode,
+			&document.MeasureType,
+			&document.MeasureTypeDescription,
+			&document.GeographicalArea,
+			&document.AdditionalCode,
+			&document.ConditionCode,
+			&document.SequenceNumber,
+			&document.ActionCode,
+			&document.DutyAmount,
+			&document.Unit,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan measure condition: %v", err)
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, rows.Err()
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// thirdCountryDutyMeasureType is the measure type of the duty applied to goods of any origin without conditions
+const thirdCountryDutyMeasureType = "103"
+
+var (
+	// thirdCountryMeasureTypes are the measure types of duties applied regardless of preferential origin
+	thirdCountryMeasureTypes = []string{
+		"103", // Third country duty
+		"105", // Non preferential duty under end-use
+	}
+	// preferentialMeasureTypes are the measure types of duties applied to goods of preferential origin
+	preferentialMeasureTypes = []string{
+		"142", // Tariff preference
+		"143", // Preferential tariff quota
+		"145", // Preferential suspension
+		"146", // Preferential tariff quota under end-use
+	}
+)
+
+// DutyLineResponse is a single applicable measure with its evaluated duty
+type DutyLineResponse struct {
+	GoodsCode              string          `json:"goods_code"` // Goods code the measure is defined on, may be an ancestor of the requested code
+	MeasureType            string          `json:"measure_type"`
+	MeasureTypeDescription string          `json:"measure_type_description"`
+	Preferential           bool            `json:"preferential"`
+	GeographicalArea       string          `json:"geographical_area"`
+	AdditionalCode         string          `json:"additional_code,omitempty"`
+	OrderNumber            string          `json:"order_number,omitempty"`
+	Regulation             string          `json:"regulation"`
+	StartDate              string          `json:"start_date"`
+	EndDate                *string         `json:"end_date"`
+	DutyExpression         string          `json:"duty_expression"`
+	Footnotes              []string        `json:"footnotes"` // Footnotes explaining conditions of the measure, e.g. "CD624"
+	Components             []DutyComponent `json:"components"`
+	Amount                 *float64        `json:"amount"` // nil when the duty cannot be calculated from the given input
+	Notes                  []string        `json:"notes"`
+}
+
+// DutyResponse is the duty breakdown for a goods code, origin and declared values
+type DutyResponse struct {
+	GoodsCode          string             `json:"goods_code"`
+	Origin             string             `json:"origin"`
+	AsOf               string             `json:"as_of"`
+	CustomsValue       *float64           `json:"customs_value"`
+	Quantity           *float64           `json:"quantity"`
+	SupplementaryUnits *float64           `json:"supplementary_units"`
+	ThirdCountryDuty   *float64           `json:"third_country_duty"` // Duty of the most specific unconditional third country measure
+	PreferentialDuty   *float64           `json:"preferential_duty"`  // Lowest calculated unconditional preferential duty, if any applies
+	Lines              []DutyLineResponse `json:"lines"`
+}
+
+// handleGetDuties serves GET /duties/{goods_code}
+func (s *Server) handleGetDuties(w http.ResponseWriter, r *http.Request) {
+	goodsCode, err := parseGoodsCode(r.PathValue("goods_code"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+
+	origin := strings.ToUpper(query.Get("origin"))
+	if origin == "" {
+		writeError(w, http.StatusBadRequest, "origin is required")
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input DutyInput
+	for name, target := range map[string]**float64{
+		"customs_value":       &input.CustomsValue,
+		"quantity":            &input.NetMass,
+		"supplementary_units": &input.SupplementaryUnits,
+	} {
+		*target, err = parseOptionalAmount(query.Get(name))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+			return
+		}
+	}
+
+	lines, err := s.findDutyLines(r.Context(), goodsCode, origin, asOf, input)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("goods code %s not valid on %s", goodsCode, formatDate(asOf)))
+		return
+	}
+	if err != nil {
+		log.Printf("Error calculating duties of goods code %s: %v", goodsCode, err)
+		writeError(w, http.StatusInternalServerError, "failed to calculate duties")
+		return
+	}
+
+	response := DutyResponse{
+		GoodsCode:          goodsCode,
+		Origin:             origin,
+		AsOf:               formatDate(asOf),
+		CustomsValue:       input.CustomsValue,
+		Quantity:           input.NetMass,
+		SupplementaryUnits: input.SupplementaryUnits,
+		Lines:              lines,
+	}
+
+	response.ThirdCountryDuty = thirdCountryDuty(lines)
+	response.PreferentialDuty = preferentialDuty(lines)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// thirdCountryDuty returns the duty of the unconditional third country measure: the most specific 103 measure
+// without an additional code or quota order number. Other non preferential lines, e.g. end-use duties or duties
+// within a quota, apply only under conditions and are used only when no such measure exists.
+// Lines are ordered from the most specific goods code.
+func thirdCountryDuty(lines []DutyLineResponse) *float64 {
+	var fallback *DutyLineResponse
+	for i, line := range lines {
+		if line.Preferential {
+			continue
+		}
+		if line.MeasureType == thirdCountryDutyMeasureType && line.AdditionalCode == "" && line.OrderNumber == "" {
+			return line.Amount
+		}
+		if fallback == nil {
+			fallback = &lines[i]
+		}
+	}
+
+	if fallback == nil {
+		return nil
+	}
+	return fallback.Amount
+}
+
+// preferentialDuty returns the lowest calculated duty of the unconditional preferential measures, without an
+// additional code or quota order number. Preferential duties within a quota or for an additional code apply only
+// under conditions and are used only when no unconditional preferential measure has a calculated duty.
+func preferentialDuty(lines []DutyLineResponse) *float64 {
+	var lowest, fallback *float64
+	for _, line := range lines {
+		if !line.Preferential || line.Amount == nil {
+			continue
+		}
+		if line.AdditionalCode == "" && line.OrderNumber == "" {
+			if lowest == nil || *line.Amount < *lowest {
+				lowest = line.Amount
+			}
+		} else if fallback == nil || *line.Amount < *fallback {
+			fallback = line.Amount
+		}
+	}
+
+	if lowest == nil {
+		return fallback
+	}
+	return lowest
+}
+
+// parseOptionalAmount parses a non-negative decimal query parameter, returning nil when it is not set
+func parseOptionalAmount(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 {
+		return nil, fmt.Errorf("expected a non-negative number, got %q", value)
+	}
+
+	return &amount, nil
+}
+
+// findDutyLines loads the third country and preferential measures applicable to the goods code
+// and origin on the given date and evaluates their duty expressions.
+// Measures of all ancestors in the hierarchy_path are included, ordered from the most specific goods code.
+func (s *Server) findDutyLines(ctx context.Context, goodsCode string, origin string, asOf time.Time, input DutyInput) ([]DutyLineResponse, error) {
+	hierarchyPath, err := s.findHierarchyPath(ctx, goodsCode, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	measureTypes := append(append([]string{}, thirdCountryMeasureTypes...), preferentialMeasureTypes...)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.goods_code, m.measure_type_code, mt.description, m.geographical_area_code,
+			m.additional_code, m.order_number, m.regulation, m.start_date, m.end_date, m.duty_expression,
+			ARRAY(
+				SELECT f.footnote_type || f.code FROM footnote_measures fm
+				JOIN footnotes f ON fm.footnote_id = f.id
+				WHERE fm.measure_id = m.id
+				ORDER BY f.footnote_type, f.code
+			)
+	`+applicableMeasuresFrom+measureOriginCondition+`
+		AND m.measure_type_code = ANY($4)
+		ORDER BY ancestors.level DESC, m.goods_code DESC, m.measure_type_code, m.geographical_area_code
+	`, hierarchyPath, asOf, origin, pq.Array(measureTypes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measures: %v", err)
+	}
+	defer rows.Close()
+
+	preferential := make(map[string]bool)
+	for _, measureType := range preferentialMeasureTypes {
+		preferential[measureType] = true
+	}
+
+	lines := []DutyLineResponse{}
+	for rows.Next() {
+		var line DutyLineResponse
+		var startDate time.Time
+		var endDate sql.NullTime
+		if err := rows.Scan(
+			&line.GoodsCode,
+			&line.MeasureType,
+			&line.MeasureTypeDescription,
+			&line.GeographicalArea,
+			&line.AdditionalCode,
+			&line.OrderNumber,
+			&line.Regulation,
+			&startDate,
+			&endDate,
+			&line.DutyExpression,
+			pq.Array(&line.Footnotes),
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan measure: %v", err)
+		}
+
+		line.StartDate = formatDate(startDate)
+		line.EndDate = formatNullDate(endDate)
+		line.Preferential = preferential[line.MeasureType]
+
+		evaluation := evaluateDutyExpression(line.DutyExpression, input)
+		line.Components = evaluation.Components
+		line.Amount = evaluation.Amount
+		line.Notes = evaluation.Notes
+
+		if line.OrderNumber != "" {
+			line.Notes = append(line.Notes, fmt.Sprintf("applies only within tariff quota %s", line.OrderNumber))
+		}
+		if line.AdditionalCode != "" {
+			line.Notes = append(line.Notes, fmt.Sprintf("applies only with additional code %s", line.AdditionalCode))
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
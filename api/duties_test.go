@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestThirdCountryDuty(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	endUse := DutyLineResponse{MeasureType: "105", Amount: value(1)}
+	withAdditionalCode := DutyLineResponse{MeasureType: "103", AdditionalCode: "C490", Amount: value(2)}
+	withinQuota := DutyLineResponse{MeasureType: "103", OrderNumber: "091104", Amount: value(3)}
+	thirdCountry := DutyLineResponse{MeasureType: "103", Amount: value(4)}
+	ancestorThirdCountry := DutyLineResponse{MeasureType: "103", Amount: value(5)}
+	preferential := DutyLineResponse{MeasureType: "142", Preferential: true, Amount: value(0)}
+
+	tests := []struct {
+		name     string
+		lines    []DutyLineResponse
+		expected *float64
+	}{
+		{"Conditional lines before the plain measure", []DutyLineResponse{preferential, endUse, withAdditionalCode, withinQuota, thirdCountry}, value(4)},
+		{"Most specific plain measure", []DutyLineResponse{thirdCountry, ancestorThirdCountry}, value(4)},
+		{"Only conditional lines", []DutyLineResponse{preferential, withinQuota, endUse}, value(3)},
+		{"Only preferential lines", []DutyLineResponse{preferential}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := thirdCountryDuty(tc.lines)
+
+			switch {
+			case tc.expected == nil && result != nil:
+				t.Errorf("thirdCountryDuty() = %.2f, expected no duty", *result)
+			case tc.expected != nil && result == nil:
+				t.Errorf("thirdCountryDuty() returned no duty, expected %.2f", *tc.expected)
+			case tc.expected != nil && *result != *tc.expected:
+				t.Errorf("thirdCountryDuty() = %.2f, expected %.2f", *result, *tc.expected)
+			}
+		})
+	}
+}
+
+func TestPreferentialDuty(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	withinQuota := DutyLineResponse{MeasureType: "143", Preferential: true, OrderNumber: "092011", Amount: value(0)}
+	withAdditionalCode := DutyLineResponse{MeasureType: "142", Preferential: true, AdditionalCode: "C490", Amount: value(1)}
+	gsp := DutyLineResponse{MeasureType: "142", Preferential: true, Amount: value(3)}
+	agreement := DutyLineResponse{MeasureType: "142", Preferential: true, Amount: value(2)}
+	notCalculated := DutyLineResponse{MeasureType: "142", Preferential: true}
+	thirdCountry := DutyLineResponse{MeasureType: "103", Amount: value(0)}
+
+	tests := []struct {
+		name     string
+		lines    []DutyLineResponse
+		expected *float64
+	}{
+		{"Conditional lines with lower duties", []DutyLineResponse{thirdCountry, withinQuota, withAdditionalCode, gsp}, value(3)},
+		{"Lowest plain measure", []DutyLineResponse{gsp, agreement, notCalculated}, value(2)},
+		{"Only conditional lines", []DutyLineResponse{withAdditionalCode, withinQuota}, value(0)},
+		{"Only duties not calculated", []DutyLineResponse{notCalculated, withAdditionalCode}, value(1)},
+		{"No preferential lines", []DutyLineResponse{thirdCountry}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := preferentialDuty(tc.lines)
+
+			switch {
+			case tc.expected == nil && result != nil:
+				t.Errorf("preferentialDuty() = %.2f, expected no duty", *result)
+			case tc.expected != nil && result == nil:
+				t.Errorf("preferentialDuty() returned no duty, expected %.2f", *tc.expected)
+			case tc.expected != nil && *result != *tc.expected:
+				t.Errorf("preferentialDuty() = %.2f, expected %.2f", *result, *tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DutyInput holds the declared values a duty expression is evaluated against.
+// Values that were not declared are nil, so components depending on them cannot be calculated.
+type DutyInput struct {
+	CustomsValue       *float64 // Customs value in EUR
+	NetMass            *float64 // Net mass in kg
+	SupplementaryUnits *float64 // Quantity in the supplementary unit of the goods code (p/st, l, hl, ...)
+}
+
+// DutyComponent is a single part of a duty expression, e.g. "+ 176.80 EUR / 100 kg"
+type DutyComponent struct {
+	Operator   string   `json:"operator"`   // "", "+", "-", "MIN" or "MAX"
+	Expression string   `json:"expression"` // The component without its operator
+	Amount     *float64 `json:"amount"`     // Calculated amount in EUR, nil if it cannot be calculated
+	Note       string   `json:"note,omitempty"`
+	Condition  string   `json:"condition,omitempty"` // Condition of the branch of a conditional duty, e.g. "V 47.300 EUR/100 kg"
+	Selected   bool     `json:"selected,omitempty"`  // The branch of the component applies to the given input
+}
+
+// DutyEvaluation is the result of evaluating a whole duty expression
+type DutyEvaluation struct {
+	Components []DutyComponent
+	Amount     *float64 // Total amount in EUR, nil if any component cannot be calculated
+	Notes      []string
+}
+
+var (
+	// adValoremPattern matches a percentage of the customs value, e.g. "12.80 %"
+	adValoremPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*%$`)
+	// specificPattern matches an amount per unit, e.g. "176.80 EUR / 100 kg"
+	specificPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s+([A-Z]{3})\s*/\s*(.+)$`)
+	// unitPattern splits a unit into its optional scale and name, e.g. "100 kg" or "hl"
+	unitPattern = regexp.MustCompile(`^(?:(\d+)\s+)?(.+)$`)
+	// agriculturalPattern matches agricultural components (Meursing table), e.g. "EA", "ADSZR"
+	agriculturalPattern = regexp.MustCompile(`^(EA|AC|ADSZ|ADFM)\s*R?$`)
+	// conditionPattern matches a branch of a conditional duty, e.g. "V(01):47.300 EUR/100 kg (01):13.00 %"
+	conditionPattern = regexp.MustCompile(`^([A-Z]{1,2})\s*\((\d{2})\)\s*:\s*(.*?)\s*\((\d{2})\)\s*:\s*(.*)$`)
+)
+
+// entryPriceCondition is the condition code of entry price branches, applying the duty of the branch
+// with the highest reference price not above the entry price
+const entryPriceCondition = "V"
+
+// dutyOperators are the tokens separating the components of a duty expression
+var dutyOperators = map[string]bool{"+": true, "-": true, "MIN": true, "MAX": true}
+
+// evaluateDutyExpression splits a TARIC duty expression into its components and calculates
+// the duty for the given input. Supported components are ad valorem rates, specific amounts
+// per mass or supplementary unit, and MIN/MAX limits. Conditional duties are evaluated by
+// evaluateConditionalDuty. Agricultural components depend on the product composition, which
+// is not available here, and are reported in the notes.
+func evaluateDutyExpression(expression string, input DutyInput) DutyEvaluation {
+	evaluation := DutyEvaluation{Components: []DutyComponent{}, Notes: []string{}}
+
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		evaluation.Notes = append(evaluation.Notes, "measure has no duty expression")
+		return evaluation
+	}
+
+	if strings.HasPrefix(strings.ToUpper(expression), "COND:") {
+		return evaluateConditionalDuty(strings.TrimSpace(expression[len("COND:"):]), input)
+	}
+
+	evaluation.Components, evaluation.Amount, evaluation.Notes = evaluateDutyComponents(expression, input)
+	return evaluation
+}
+
+// evaluateDutyComponents calculates the components of an expression without conditions.
+// The total is nil if any component cannot be calculated, the notes explain why.
+func evaluateDutyComponents(expression string, input DutyInput) ([]DutyComponent, *float64, []string) {
+	components := []DutyComponent{}
+	notes := []string{}
+	total := 0.0
+	complete := true
+
+	for _, component := range splitDutyExpression(expression) {
+		component.Amount, component.Note = evaluateDutyComponent(component.Expression, input)
+		components = append(components, component)
+
+		if component.Amount == nil {
+			complete = false
+			if component.Note != "" {
+				notes = append(notes, component.Note)
+			}
+			continue
+		}
+
+		amount := *component.Amount
+		switch component.Operator {
+		case "", "+":
+			total += amount
+		case "-":
+			total -= amount
+		case "MIN":
+			total = math.Max(total, amount)
+		case "MAX":
+			total = math.Min(total, amount)
+		}
+	}
+
+	if !complete {
+		return components, nil, notes
+	}
+
+	return components, roundAmount(total), notes
+}
+
+// dutyCondition is a branch of a conditional duty, e.g. "V(01):47.300 EUR/100 kg (01):13.00 % + 2.20 EUR/100 kg"
+type dutyCondition struct {
+	Code      string // Condition code, e.g. "V" entry price
+	Reference string // Reference amount of the condition, e.g. "47.300 EUR/100 kg", empty if it has none
+	Duty      string // Duty expression applied when the condition is met, empty if the measure does not apply
+}
+
+// parseDutyConditions splits the branches of a conditional duty, given without its "Cond:" prefix
+func parseDutyConditions(expression string) ([]dutyCondition, error) {
+	var conditions []dutyCondition
+	for _, branch := range strings.Split(expression, ";") {
+		branch = strings.TrimSpace(branch)
+		if branch == "" {
+			continue
+		}
+
+		match := conditionPattern.FindStringSubmatch(branch)
+		if match == nil {
+			return nil, fmt.Errorf("unsupported condition %s", branch)
+		}
+		conditions = append(conditions, dutyCondition{Code: match[1], Reference: match[3], Duty: strings.TrimSpace(match[5])})
+	}
+
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("conditional duty has no conditions")
+	}
+
+	return conditions, nil
+}
+
+// evaluateConditionalDuty lists the components of every branch of a conditional duty and calculates the duty of
+// the branch applying to the input. Entry price branches are selected by the entry price, the customs value per
+// unit of the reference amount; branches of other conditions depend on documents presented with the declaration
+// and are only listed.
+func evaluateConditionalDuty(expression string, input DutyInput) DutyEvaluation {
+	evaluation := DutyEvaluation{Components: []DutyComponent{}, Notes: []string{}}
+
+	conditions, err := parseDutyConditions(expression)
+	if err != nil {
+		evaluation.Notes = append(evaluation.Notes, err.Error())
+		return evaluation
+	}
+
+	selected, note := selectEntryPriceCondition(conditions, input)
+	if note != "" {
+		evaluation.Notes = append(evaluation.Notes, note)
+	}
+
+	for i, condition := range conditions {
+		label := condition.Code
+		if condition.Reference != "" {
+			label = fmt.Sprintf("%s %s", condition.Code, condition.Reference)
+		}
+
+		components, amount, notes := evaluateDutyComponents(condition.Duty, input)
+		for _, component := range components {
+			component.Condition = label
+			component.Selected = i == selected
+			evaluation.Components = append(evaluation.Components, component)
+		}
+
+		if i != selected {
+			continue
+		}
+		if condition.Duty == "" {
+			evaluation.Notes = append(evaluation.Notes, fmt.Sprintf("condition %s applies no duty", label))
+			continue
+		}
+		evaluation.Amount = amount
+		evaluation.Notes = append(evaluation.Notes, notes...)
+	}
+
+	return evaluation
+}
+
+// selectEntryPriceCondition returns the index of the entry price branch applying to the input: the branch with
+// the highest reference price not above the entry price. It returns -1 and an explanation when no branch can be
+// selected.
+func selectEntryPriceCondition(conditions []dutyCondition, input DutyInput) (int, string) {
+	selected := -1
+	selectedReference := 0.0
+	for i, condition := range conditions {
+		if condition.Code != entryPriceCondition {
+			return -1, fmt.Sprintf("condition %s depends on the documents presented and cannot be calculated automatically", condition.Code)
+		}
+
+		match := specificPattern.FindStringSubmatch(condition.Reference)
+		if match == nil || match[2] != "EUR" {
+			return -1, fmt.Sprintf("unsupported entry price %s", condition.Reference)
+		}
+		reference, _ := strconv.ParseFloat(match[1], 64)
+
+		if input.CustomsValue == nil {
+			return -1, "customs value is required to select the entry price condition"
+		}
+		quantity, note := quantityInUnit(match[3], input)
+		if quantity == nil {
+			return -1, fmt.Sprintf("%s to select the entry price condition", note)
+		}
+		if *quantity == 0 {
+			return -1, "a quantity above zero is required to select the entry price condition"
+		}
+
+		entryPrice := *input.CustomsValue / *quantity
+		if entryPrice >= reference && (selected < 0 || reference > selectedReference) {
+			selected, selectedReference = i, reference
+		}
+	}
+
+	if selected < 0 {
+		return -1, "entry price is below every condition of the measure"
+	}
+
+	return selected, ""
+}
+
+// splitDutyExpression splits a duty expression into components at its operators
+func splitDutyExpression(expression string) []DutyComponent {
+	// Operators may be glued to the following component, e.g. "+ADSZ"
+	tokens := strings.Fields(strings.ReplaceAll(expression, "+", " + "))
+
+	components := []DutyComponent{}
+	current := DutyComponent{}
+	parts := []string{}
+
+	for _, token := range tokens {
+		if dutyOperators[strings.ToUpper(token)] {
+			if len(parts) > 0 {
+				current.Expression = strings.Join(parts, " ")
+				components = append(components, current)
+			}
+			current = DutyComponent{Operator: strings.ToUpper(token)}
+			parts = []string{}
+			continue
+		}
+		parts = append(parts, token)
+	}
+
+	if len(parts) > 0 {
+		current.Expression = strings.Join(parts, " ")
+		components = append(components, current)
+	}
+
+	return components
+}
+
+// evaluateDutyComponent calculates the amount of a single component.
+// Returns nil and an explanation when the amount cannot be calculated.
+func evaluateDutyComponent(expression string, input DutyInput) (*float64, string) {
+	if strings.EqualFold(expression, "NIHIL") {
+		return roundAmount(0), ""
+	}
+
+	if match := adValoremPattern.FindStringSubmatch(expression); match != nil {
+		rate, _ := strconv.ParseFloat(match[1], 64)
+		if input.CustomsValue == nil {
+			return nil, fmt.Sprintf("customs value is required for %s", expression)
+		}
+		return roundAmount(rate / 100 * *input.CustomsValue), ""
+	}
+
+	if match := specificPattern.FindStringSubmatch(expression); match != nil {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		if match[2] != "EUR" {
+			return nil, fmt.Sprintf("unsupported currency %s in %s", match[2], expression)
+		}
+
+		quantity, note := quantityInUnit(match[3], input)
+		if quantity == nil {
+			return nil, fmt.Sprintf("%s for %s", note, expression)
+		}
+		return roundAmount(amount * *quantity), ""
+	}
+
+	if agriculturalPattern.MatchString(strings.ToUpper(expression)) {
+		return nil, fmt.Sprintf("agricultural component %s depends on the product composition and cannot be calculated", expression)
+	}
+
+	return nil, fmt.Sprintf("unsupported duty component %s", expression)
+}
+
+// quantityInUnit converts the declared quantity into multiples of the given unit, e.g. "100 kg".
+// Mass units use the net mass, any other unit uses the supplementary units.
+func quantityInUnit(unit string, input DutyInput) (*float64, string) {
+	match := unitPattern.FindStringSubmatch(strings.TrimSpace(unit))
+	scale := 1.0
+	if match[1] != "" {
+		scale, _ = strconv.ParseFloat(match[1], 64)
+	}
+	name := strings.ToLower(match[2])
+
+	switch {
+	case name == "t" || strings.HasPrefix(name, "tonne"):
+		if input.NetMass == nil {
+			return nil, "net mass is required"
+		}
+		quantity := *input.NetMass / 1000 / scale
+		return &quantity, ""
+	case strings.HasPrefix(name, "kg"):
+		if input.NetMass == nil {
+			return nil, "net mass is required"
+		}
+		quantity := *input.NetMass / scale
+		return &quantity, ""
+	default:
+		if input.SupplementaryUnits == nil {
+			return nil, fmt.Sprintf("supplementary units (%s) are required", name)
+		}
+		quantity := *input.SupplementaryUnits / scale
+		return &quantity, ""
+	}
+}
+
+// roundAmount rounds an amount to cents
+func roundAmount(amount float64) *float64 {
+	rounded := math.Round(amount*100) / 100
+	return &rounded
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateDutyExpression(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	input := DutyInput{
+		CustomsValue:       value(1000),
+		NetMass:            value(250),
+		SupplementaryUnits: value(40),
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		input      DutyInput
+		expected   *float64
+		components int
+	}{
+		// Ad valorem and specific duties
+		{"Zero duty", "0.00 %", input, value(0), 1},
+		{"Ad valorem", "12.80 %", input, value(128), 1},
+		{"Specific per 100 kg", "176.80 EUR / 100 kg", input, value(442), 1},
+		{"Specific per kg", "0.50 EUR / kg", input, value(125), 1},
+		{"Specific per tonne", "20.00 EUR / 1000 kg", input, value(5), 1},
+		{"Specific per supplementary unit", "2.00 EUR / 100 p/st", input, value(0.8), 1},
+		{"Nihil", "NIHIL", input, value(0), 1},
+
+		// Combined duties
+		{"Ad valorem plus specific", "9.60 % + 176.80 EUR / 100 kg", input, value(538), 2},
+		{"Minimum applies", "2.00 % MIN 10.00 EUR / 100 kg", input, value(25), 2},
+		{"Minimum does not apply", "12.00 % MIN 1.30 EUR / 100 kg", input, value(120), 2},
+		{"Maximum applies", "20.00 % MAX 50.00 EUR / 100 kg", input, value(125), 2},
+
+		// Entry prices, 1000 EUR for 250 kg is an entry price of 400 EUR / 100 kg
+		{"Entry price single condition", "Cond: V(01):0.000 EUR/100 kg (01):13.00 %", input, value(130), 1},
+		{"Entry price above highest condition", entryPrices(400), input, value(88), 5},
+		{"Entry price between conditions", entryPrices(405), input, value(93.5), 5},
+		{"Entry price below every condition but zero", entryPrices(1000), input, value(162.5), 5},
+
+		// Not calculable
+		{"Agricultural component", "8.30 % + EA MAX 18.70 % +ADSZ", input, nil, 4},
+		{"Entry price without quantity", entryPrices(400), DutyInput{CustomsValue: value(1000)}, nil, 5},
+		{"Condition on documents", "Cond: B(01):(01):0.00 % ; B(08):(08):", input, nil, 1},
+		{"Missing customs value", "12.80 %", DutyInput{NetMass: value(10)}, nil, 1},
+		{"Missing supplementary units", "4.20 EUR / hl", DutyInput{CustomsValue: value(10)}, nil, 1},
+		{"Unsupported currency", "4.20 ECU / hl", input, nil, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := evaluateDutyExpression(tc.expression, tc.input)
+
+			if len(result.Components) != tc.components {
+				t.Errorf("evaluateDutyExpression(%q) returned %d components, expected %d", tc.expression, len(result.Components), tc.components)
+			}
+
+			switch {
+			case tc.expected == nil && result.Amount != nil:
+				t.Errorf("evaluateDutyExpression(%q) = %.2f, expected no amount", tc.expression, *result.Amount)
+			case tc.expected == nil && len(result.Notes) == 0:
+				t.Errorf("evaluateDutyExpression(%q) returned no notes explaining the missing amount", tc.expression)
+			case tc.expected != nil && result.Amount == nil:
+				t.Errorf("evaluateDutyExpression(%q) returned no amount, expected %.2f (notes: %v)", tc.expression, *tc.expected, result.Notes)
+			case tc.expected != nil && *result.Amount != *tc.expected:
+				t.Errorf("evaluateDutyExpression(%q) = %.2f, expected %.2f", tc.expression, *result.Amount, *tc.expected)
+			}
+		})
+	}
+}
+
+// entryPrices returns an entry price duty of 8.80 % with the specific duty rising below the given reference price
+func entryPrices(highest float64) string {
+	return fmt.Sprintf("Cond: V(01):%.3f EUR/100 kg (01):8.80 %% ; V(01):%.3f EUR/100 kg (01):8.80 %% + 2.20 EUR/100 kg ; V(01):0.000 EUR/100 kg (01):8.80 %% + 29.80 EUR/100 kg", highest, highest-10)
+}
+
+func TestEvaluateConditionalDutySelectsBranch(t *testing.T) {
+	customsValue, netMass := 1000.0, 250.0
+	input := DutyInput{CustomsValue: &customsValue, NetMass: &netMass}
+
+	// 400 EUR / 100 kg is below the highest condition and at the second one
+	result := evaluateDutyExpression(entryPrices(410), input)
+
+	var selected []string
+	for _, component := range result.Components {
+		if component.Selected {
+			selected = append(selected, component.Condition+": "+component.Expression)
+		}
+	}
+	expected := []string{"V 400.000 EUR/100 kg: 8.80 %", "V 400.000 EUR/100 kg: 2.20 EUR/100 kg"}
+	if fmt.Sprint(selected) != fmt.Sprint(expected) {
+		t.Errorf("evaluateDutyExpression() selected %q, expected %q", selected, expected)
+	}
+	if result.Amount == nil || *result.Amount != 93.5 {
+		t.Errorf("evaluateDutyExpression() = %v, expected 93.50", result.Amount)
+	}
+}
@@ -0,0 +1,3 @@
+module muj/api
+
+go 1.23.4
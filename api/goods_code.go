@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// defaultProductLineSuffix is used when a goods code is requested without a suffix.
+// Suffix "80" marks the line representing actual goods rather than an intermediary heading.
+const defaultProductLineSuffix = "80"
+
+// parseGoodsCode normalizes a goods code taken from a request into the
+// "0102909100 80" form stored in nomenclatures.goods_code.
+// Spaces, dots and dashes are ignored. Codes shorter than 10 digits are padded with
+// pairs of zeroes (e.g. chapter "01" becomes "0100000000"), and a 12 digit code
+// is treated as a goods code followed by its product line suffix.
+func parseGoodsCode(input string) (string, error) {
+	var digits strings.Builder
+	for _, r := range input {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == ' ' || r == '.' || r == '-':
+			continue
+		default:
+			return "", fmt.Errorf("invalid goods code %q: unexpected character %q", input, r)
+		}
+	}
+
+	code := digits.String()
+	suffix := defaultProductLineSuffix
+
+	switch {
+	case len(code) == 12:
+		suffix = code[10:]
+		code = code[:10]
+	case len(code) > 0 && len(code) <= 10 && len(code)%2 == 0:
+		code += strings.Repeat("0", 10-len(code))
+	default:
+		return "", fmt.Errorf("invalid goods code %q: expected 2 to 10 digits with an optional 2 digit suffix", input)
+	}
+
+	return code + " " + suffix, nil
+}
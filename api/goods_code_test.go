@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseGoodsCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		// Full goods codes
+		{"Goods code without suffix", "0102909100", "0102909100 80", false},
+		{"Goods code with suffix", "010290910010", "0102909100 10", false},
+		{"Goods code with spaced suffix", "0102909100 10", "0102909100 10", false},
+		{"Goods code with spaces", "0102 90 91 00", "0102909100 80", false},
+		{"Goods code with dots", "0102.90.91", "0102909100 80", false},
+
+		// Shorter codes are padded
+		{"Chapter", "01", "0100000000 80", false},
+		{"Heading", "0102", "0102000000 80", false},
+		{"CN code", "01029091", "0102909100 80", false},
+
+		// Invalid codes
+		{"Empty string", "", "", true},
+		{"Odd length", "010", "", true},
+		{"Too long", "0102909100801", "", true},
+		{"Letters", "01AB", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseGoodsCode(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("parseGoodsCode(%q) = %q, expected an error", tc.input, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGoodsCode(%q) returned error: %v", tc.input, err)
+			}
+			if result != tc.expected {
+				t.Errorf("parseGoodsCode(%q) = %q, expected %q", tc.input, result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultLanguage is used for tree descriptions when no language is requested
+const defaultLanguage = "EN"
+
+// TreeNodeResponse is a goods code within the nomenclature tree with its description in one language
+type TreeNodeResponse struct {
+	ID            int     `json:"id"`
+	GoodsCode     string  `json:"goods_code"`
+	HierarchyPath string  `json:"hierarchy_path"`
+	Indent        int     `json:"indent"`
+	IsLeaf        *bool   `json:"is_leaf"`
+	Description   *string `json:"description"` // nil when the code has no description in the requested language
+}
+
+// TreeResponse is the result of a hierarchy query relative to a goods code, as valid on a given date
+type TreeResponse struct {
+	GoodsCode string             `json:"goods_code"`
+	Language  string             `json:"language"`
+	AsOf      string             `json:"as_of"`
+	Nodes     []TreeNodeResponse `json:"nodes"`
+}
+
+// treeQuery selects nodes of the tree relative to a goods code.
+// The condition receives the hierarchy path of the goods code as $2 and the as_of date as $3.
+type treeQuery struct {
+	condition   string
+	orderBy     string
+	excludeSelf bool // Whether the goods code itself is left out of the result
+}
+
+var (
+	// childrenQuery selects codes exactly one level below the goods code
+	childrenQuery = treeQuery{
+		condition: "n.hierarchy_path ~ ($2::text || '.*{1}')::lquery",
+		orderBy:   "n.goods_code",
+	}
+	// ancestorsQuery selects codes above the goods code, from the chapter down, forming a breadcrumb
+	ancestorsQuery = treeQuery{
+		condition:   "n.hierarchy_path @> $2::ltree",
+		orderBy:     "nlevel(n.hierarchy_path), n.indent, n.goods_code",
+		excludeSelf: true,
+	}
+	// subtreeQuery selects the goods code and all codes below it
+	subtreeQuery = treeQuery{
+		condition: "n.hierarchy_path <@ $2::ltree",
+		orderBy:   "n.goods_code",
+	}
+)
+
+// handleGetChildren serves GET /nomenclatures/{goods_code}/children
+func (s *Server) handleGetChildren(w http.ResponseWriter, r *http.Request) {
+	s.serveTree(w, r, childrenQuery)
+}
+
+// handleGetAncestors serves GET /nomenclatures/{goods_code}/ancestors
+func (s *Server) handleGetAncestors(w http.ResponseWriter, r *http.Request) {
+	s.serveTree(w, r, ancestorsQuery)
+}
+
+// handleGetSubtree serves GET /nomenclatures/{goods_code}/subtree
+func (s *Server) handleGetSubtree(w http.ResponseWriter, r *http.Request) {
+	s.serveTree(w, r, subtreeQuery)
+}
+
+// serveTree handles the common request parsing and response writing of the hierarchy endpoints
+func (s *Server) serveTree(w http.ResponseWriter, r *http.Request, query treeQuery) {
+	goodsCode, err := parseGoodsCode(r.PathValue("goods_code"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	language, err := parseLanguage(r.URL.Query().Get("lang"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	nodes, err := s.findTree(r.Context(), goodsCode, language, asOf, query)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("goods code %s not valid on %s", goodsCode, formatDate(asOf)))
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying hierarchy of goods code %s: %v", goodsCode, err)
+		writeError(w, http.StatusInternalServerError, "failed to query hierarchy")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TreeResponse{
+		GoodsCode: goodsCode,
+		Language:  language,
+		AsOf:      formatDate(asOf),
+		Nodes:     nodes,
+	})
+}
+
+// parseLanguage validates a two letter language code, falling back to the default language when empty
+func parseLanguage(input string) (string, error) {
+	if input == "" {
+		return defaultLanguage, nil
+	}
+
+	language := strings.ToUpper(input)
+	if len(language) != 2 || strings.IndexFunc(language, func(r rune) bool { return r < 'A' || r > 'Z' }) != -1 {
+		return "", fmt.Errorf("invalid language %q: expected a two letter language code", input)
+	}
+
+	return language, nil
+}
+
+// findTree resolves the hierarchy path of the goods code and selects the related nodes.
+// Only codes valid on the given date are returned, with the description valid on that date.
+func (s *Server) findTree(ctx context.Context, goodsCode string, language string, asOf time.Time, query treeQuery) ([]TreeNodeResponse, error) {
+	var id int
+	var hierarchyPath string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, hierarchy_path FROM nomenclatures
+		WHERE goods_code = $1 AND start_date <= $2 AND (end_date IS NULL OR end_date >= $2)
+		ORDER BY start_date DESC
+		LIMIT 1
+	`, goodsCode, asOf).Scan(&id, &hierarchyPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nomenclature: %v", err)
+	}
+
+	condition := query.condition
+	args := []interface{}{language, hierarchyPath, asOf}
+	if query.excludeSelf {
+		condition += " AND n.id <> $4"
+		args = append(args, id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT n.id, n.goods_code, n.hierarchy_path, n.indent, dc.is_leaf, nd.description
+		FROM nomenclatures n
+		LEFT JOIN nomenclature_declarable_codes dc ON n.id = dc.nomenclature_id
+		LEFT JOIN nomenclature_descriptions nd ON n.id = nd.nomenclature_id AND nd.language = $1
+			AND nd.descr_start_date = (
+				SELECT MAX(d.descr_start_date) FROM nomenclature_descriptions d
+				WHERE d.nomenclature_id = n.id AND d.language = $1 AND d.descr_start_date <= $3
+			)
+		WHERE n.start_date <= $3 AND (n.end_date IS NULL OR n.end_date >= $3)
+		AND %s
+		ORDER BY %s
+	`, condition, query.orderBy), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tree: %v", err)
+	}
+	defer rows.Close()
+
+	nodes := []TreeNodeResponse{}
+	for rows.Next() {
+		var node TreeNodeResponse
+		var isLeaf sql.NullBool
+		var description sql.NullString
+		if err := rows.Scan(&node.ID, &node.GoodsCode, &node.HierarchyPath, &node.Indent, &isLeaf, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan tree node: %v", err)
+		}
+
+		if isLeaf.Valid {
+			node.IsLeaf = &isLeaf.Bool
+		}
+		if description.Valid {
+			node.Description = &description.String
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, rows.Err()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"muj/database"
+	"net/http"
+)
+
+func main() {
+	// Parse command line arguments
+	addr := flag.String("addr", ":8080", "Address for the HTTP server to listen on")
+	flag.Parse()
+
+	// Connect to database
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	server := NewServer(db)
+
+	log.Printf("API server listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, server.Routes()); err != nil {
+		log.Fatal(err)
+	}
+}
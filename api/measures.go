@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ergaOmnes is the geographical area covering all countries of the world
+const ergaOmnes = "1011"
+
+// applicableMeasuresFrom selects the measures m, with their measure type mt, valid on the date $2 and defined on
+// the goods code with hierarchy path $1 or any of its ancestors. TARIC measures are inherited by descendant codes,
+// the level of the code a measure is defined on is available as ancestors.level.
+const applicableMeasuresFrom = `
+	FROM measures m
+	JOIN measure_types mt ON m.measure_type_code = mt.code
+	JOIN (
+		SELECT goods_code, MAX(nlevel(hierarchy_path)) AS level
+		FROM nomenclatures
+		WHERE hierarchy_path @> $1::ltree AND start_date <= $2 AND (end_date IS NULL OR end_date >= $2)
+		GROUP BY goods_code
+	) ancestors ON m.goods_code = ancestors.goods_code
+	WHERE m.start_date <= $2 AND (m.end_date IS NULL OR m.end_date >= $2)
+`
+
+// measureOriginCondition restricts applicable measures to the origin $3: measures defined for the origin itself,
+// for ERGA OMNES, or for a country group the origin is a member of on the date $2, unless the origin is excluded
+// from the measure.
+const measureOriginCondition = `
+	AND (
+		m.geographical_area_code IN ($3, '` + ergaOmnes + `')
+		OR EXISTS (
+			SELECT 1 FROM geographical_area_memberships gm
+			WHERE gm.group_id = m.geographical_area_code AND gm.member_id = $3
+			AND gm.start_date <= $2 AND (gm.end_date IS NULL OR gm.end_date >= $2)
+		)
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM measure_excluded_areas ex
+		WHERE ex.measure_id = m.id AND ex.geographical_area_code = $3
+	)
+`
+
+// findHierarchyPath returns the hierarchy path of the goods code as valid on the given date
+func (s *Server) findHierarchyPath(ctx context.Context, goodsCode string, asOf time.Time) (string, error) {
+	var hierarchyPath string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT hierarchy_path FROM nomenclatures
+		WHERE goods_code = $1 AND start_date <= $2 AND (end_date IS NULL OR end_date >= $2)
+		ORDER BY start_date DESC
+		LIMIT 1
+	`, goodsCode, asOf).Scan(&hierarchyPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query nomenclature: %v", err)
+	}
+
+	return hierarchyPath, nil
+}
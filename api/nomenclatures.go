@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NomenclatureResponse is the API representation of a single goods code as valid on a given date
+type NomenclatureResponse struct {
+	AsOf              string                `json:"as_of"`
+	ID                int                   `json:"id"`
+	GoodsCode         string                `json:"goods_code"`
+	StartDate         string                `json:"start_date"`
+	EndDate           *string               `json:"end_date"`
+	HierarchyPath     string                `json:"hierarchy_path"`
+	Indent            int                   `json:"indent"`
+	IsLeaf            *bool                 `json:"is_leaf"` // nil when no declarable code information was imported
+	Descriptions      []DescriptionResponse `json:"descriptions"`
+	Section           *SectionResponse      `json:"section"`
+	Footnotes         []FootnoteResponse    `json:"footnotes"`
+	QuotaOrderNumbers []string              `json:"quota_order_numbers"` // Tariff quotas the goods code may be imported under
+}
+
+// DescriptionResponse is a goods code description in a single language, valid from its start date
+type DescriptionResponse struct {
+	Language       string `json:"language"`
+	Description    string `json:"description"`
+	DescrStartDate string `json:"descr_start_date"`
+}
+
+// FootnoteResponse is a footnote associated with a goods code, with its descriptions in all imported languages
+type FootnoteResponse struct {
+	Code         string                        `json:"code"` // Footnote type and code, e.g. "TN701"
+	Descriptions []FootnoteDescriptionResponse `json:"descriptions"`
+}
+
+// FootnoteDescriptionResponse is a footnote description in a single language
+type FootnoteDescriptionResponse struct {
+	Language    string `json:"language"`
+	Description string `json:"description"`
+}
+
+// SectionResponse is the section of the nomenclature a goods code belongs to
+type SectionResponse struct {
+	Number int                   `json:"number"`
+	Names  []SectionNameResponse `json:"names"`
+}
+
+// SectionNameResponse is a section name in a single language
+type SectionNameResponse struct {
+	Language string `json:"language"`
+	Name     string `json:"name"`
+}
+
+// errNotFound is returned by lookups when the requested record does not exist
+var errNotFound = errors.New("not found")
+
+// handleGetNomenclature serves GET /nomenclatures/{goods_code}
+func (s *Server) handleGetNomenclature(w http.ResponseWriter, r *http.Request) {
+	goodsCode, err := parseGoodsCode(r.PathValue("goods_code"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	nomenclature, err := s.findNomenclature(r.Context(), goodsCode, asOf)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("goods code %s not valid on %s", goodsCode, formatDate(asOf)))
+		return
+	}
+	if err != nil {
+		log.Printf("Error looking up goods code %s: %v", goodsCode, err)
+		writeError(w, http.StatusInternalServerError, "failed to look up goods code")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nomenclature)
+}
+
+// findNomenclature loads the validity period of a goods code covering the given date,
+// together with its descriptions, declarable flag, section, footnotes and quotas
+func (s *Server) findNomenclature(ctx context.Context, goodsCode string, asOf time.Time) (*NomenclatureResponse, error) {
+	result := NomenclatureResponse{AsOf: formatDate(asOf)}
+	var startDate time.Time
+	var endDate sql.NullTime
+	var isLeaf sql.NullBool
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT n.id, n.goods_code, n.start_date, n.end_date, n.hierarchy_path, n.indent, dc.is_leaf
+		FROM nomenclatures n
+		LEFT JOIN nomenclature_declarable_codes dc ON n.id = dc.nomenclature_id
+		WHERE n.goods_code = $1
+		AND n.start_date <= $2 AND (n.end_date IS NULL OR n.end_date >= $2)
+		ORDER BY n.start_date DESC
+		LIMIT 1
+	`, goodsCode, asOf).Scan(
+		&result.ID,
+		&result.GoodsCode,
+		&startDate,
+		&endDate,
+		&result.HierarchyPath,
+		&result.Indent,
+		&isLeaf,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nomenclature: %v", err)
+	}
+
+	result.StartDate = formatDate(startDate)
+	result.EndDate = formatNullDate(endDate)
+	if isLeaf.Valid {
+		result.IsLeaf = &isLeaf.Bool
+	}
+
+	result.Descriptions, err = s.findDescriptions(ctx, result.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Section, err = s.findSection(ctx, result.GoodsCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Footnotes, err = s.findFootnotes(ctx, result.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	result.QuotaOrderNumbers, err = s.findQuotaOrderNumbers(ctx, result.HierarchyPath, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// findDescriptions loads the descriptions of a nomenclature in all imported languages.
+// For each language only the description period started most recently before the given date is returned.
+func (s *Server) findDescriptions(ctx context.Context, nomenclatureID int, asOf time.Time) ([]DescriptionResponse, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (language) language, description, descr_start_date
+		FROM nomenclature_descriptions
+		WHERE nomenclature_id = $1 AND descr_start_date <= $2
+		ORDER BY language, descr_start_date DESC
+	`, nomenclatureID, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query descriptions: %v", err)
+	}
+	defer rows.Close()
+
+	descriptions := []DescriptionResponse{}
+	for rows.Next() {
+		var description DescriptionResponse
+		var descrStartDate time.Time
+		if err := rows.Scan(&description.Language, &description.Description, &descrStartDate); err != nil {
+			return nil, fmt.Errorf("failed to scan description: %v", err)
+		}
+		description.DescrStartDate = formatDate(descrStartDate)
+		descriptions = append(descriptions, description)
+	}
+
+	return descriptions, rows.Err()
+}
+
+// findSection loads the section of the chapter the goods code belongs to.
+// Returns nil if the chapter is not mapped to any section.
+func (s *Server) findSection(ctx context.Context, goodsCode string) (*SectionResponse, error) {
+	chapter, err := strconv.Atoi(goodsCode[:2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid chapter in goods code %s: %v", goodsCode, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT scm.section_number, sd.language, sd.name
+		FROM section_chapter_mapping scm
+		JOIN section_descriptions sd ON scm.section_number = sd.section_number
+		WHERE scm.chapter_id = $1
+		ORDER BY sd.language
+	`, chapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query section: %v", err)
+	}
+	defer rows.Close()
+
+	var section *SectionResponse
+	for rows.Next() {
+		var number int
+		var name SectionNameResponse
+		if err := rows.Scan(&number, &name.Language, &name.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan section: %v", err)
+		}
+
+		if section == nil {
+			section = &SectionResponse{Number: number, Names: []SectionNameResponse{}}
+		}
+		section.Names = append(section.Names, name)
+	}
+
+	return section, rows.Err()
+}
+
+// findFootnotes loads the footnotes associated with a nomenclature that are valid on the given date
+func (s *Server) findFootnotes(ctx context.Context, nomenclatureID int, asOf time.Time) ([]FootnoteResponse, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.footnote_type || f.code, fd.language, fd.description
+		FROM footnote_nomenclatures fn
+		JOIN footnotes f ON fn.footnote_id = f.id
+		LEFT JOIN footnote_descriptions fd ON f.id = fd.footnote_id
+		WHERE fn.nomenclature_id = $1
+		AND f.start_date <= $2 AND (f.end_date IS NULL OR f.end_date >= $2)
+		ORDER BY f.footnote_type, f.code, fd.language
+	`, nomenclatureID, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query footnotes: %v", err)
+	}
+	defer rows.Close()
+
+	footnotes := []FootnoteResponse{}
+	for rows.Next() {
+		var code string
+		var language, description sql.NullString
+		if err := rows.Scan(&code, &language, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan footnote: %v", err)
+		}
+
+		if len(footnotes) == 0 || footnotes[len(footnotes)-1].Code != code {
+			footnotes = append(footnotes, FootnoteResponse{Code: code, Descriptions: []FootnoteDescriptionResponse{}})
+		}
+		if language.Valid {
+			footnote := &footnotes[len(footnotes)-1]
+			footnote.Descriptions = append(footnote.Descriptions, FootnoteDescriptionResponse{
+				Language:    language.String,
+				Description: description.String,
+			})
+		}
+	}
+
+	return footnotes, rows.Err()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// QuotaResponse is a tariff quota order number with its definition valid on a given date
+type QuotaResponse struct {
+	OrderNumber string                   `json:"order_number"`
+	AsOf        string                   `json:"as_of"`
+	Origins     []string                 `json:"origins"`     // Origins of the definition, empty without one
+	GoodsCodes  []string                 `json:"goods_codes"` // Goods codes of the definition, empty without one
+	Definition  *QuotaDefinitionResponse `json:"definition"`  // nil when no definition period covers the date
+}
+
+// QuotaDefinitionResponse is the volume of a quota within one definition period
+type QuotaDefinitionResponse struct {
+	StartDate     string   `json:"start_date"`
+	EndDate       *string  `json:"end_date"`
+	InitialVolume float64  `json:"initial_volume"`
+	Unit          string   `json:"unit"`
+	Balance       *float64 `json:"balance"`      // Latest imported balance on or before the date, nil if none
+	BalanceDate   *string  `json:"balance_date"` // Date of the balance snapshot
+}
+
+// handleGetQuota serves GET /quotas/{order_number}
+func (s *Server) handleGetQuota(w http.ResponseWriter, r *http.Request) {
+	orderNumber, err := parseOrderNumber(r.PathValue("order_number"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	quota, err := s.findQuota(r.Context(), orderNumber, asOf)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("quota %s not found", orderNumber))
+		return
+	}
+	if err != nil {
+		log.Printf("Error looking up quota %s: %v", orderNumber, err)
+		writeError(w, http.StatusInternalServerError, "failed to look up quota")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quota)
+}
+
+// parseOrderNumber normalizes a quota order number taken from a request, e.g. "09.1104",
+// into the 6-digit form stored in quota_order_numbers.order_number
+func parseOrderNumber(input string) (string, error) {
+	var digits strings.Builder
+	for _, r := range input {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == ' ' || r == '.':
+			continue
+		default:
+			return "", fmt.Errorf("invalid order number %q: unexpected character %q", input, r)
+		}
+	}
+
+	if digits.Len() != 6 {
+		return "", fmt.Errorf("invalid order number %q: expected 6 digits", input)
+	}
+
+	return digits.String(), nil
+}
+
+// findQuota loads a quota order number with the definition valid on the given date, and its origins and goods codes
+func (s *Server) findQuota(ctx context.Context, orderNumber string, asOf time.Time) (*QuotaResponse, error) {
+	result := QuotaResponse{OrderNumber: orderNumber, AsOf: formatDate(asOf), Origins: []string{}, GoodsCodes: []string{}}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM quota_order_numbers WHERE order_number = $1)
+	`, orderNumber).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quota: %v", err)
+	}
+	if !exists {
+		return nil, errNotFound
+	}
+
+	var definition QuotaDefinitionResponse
+	var startDate time.Time
+	var endDate, balanceDate sql.NullTime
+	var balance sql.NullFloat64
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT d.start_date, d.end_date, d.initial_volume, d.unit, b.balance_date, b.balance,
+			ARRAY(SELECT geographical_area_code FROM quota_definition_origins WHERE quota_definition_id = d.id ORDER BY 1),
+			ARRAY(SELECT goods_code FROM quota_definition_goods_codes WHERE quota_definition_id = d.id ORDER BY 1)
+		FROM quota_definitions d
+		LEFT JOIN LATERAL (
+			SELECT balance_date, balance FROM quota_balances
+			WHERE quota_definition_id = d.id AND balance_date <= $2
+			ORDER BY balance_date DESC
+			LIMIT 1
+		) b ON true
+		WHERE d.order_number = $1 AND d.start_date <= $2 AND (d.end_date IS NULL OR d.end_date >= $2)
+		ORDER BY d.start_date DESC
+		LIMIT 1
+	`, orderNumber, asOf).Scan(&startDate, &endDate, &definition.InitialVolume, &definition.Unit, &balanceDate, &balance,
+		pq.Array(&result.Origins), pq.Array(&result.GoodsCodes))
+	if errors.Is(err, sql.ErrNoRows) {
+		return &result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quota definition: %v", err)
+	}
+
+	definition.StartDate = formatDate(startDate)
+	definition.EndDate = formatNullDate(endDate)
+	definition.BalanceDate = formatNullDate(balanceDate)
+	if balance.Valid {
+		definition.Balance = &balance.Float64
+	}
+	result.Definition = &definition
+
+	return &result, nil
+}
+
+// findQuotaOrderNumbers lists the quotas a goods code may be imported under on the given date: order numbers of
+// measures applicable to the code or its ancestors, and quotas whose definition valid on that date lists the code
+// or its ancestors
+func (s *Server) findQuotaOrderNumbers(ctx context.Context, hierarchyPath string, asOf time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.order_number
+	`+applicableMeasuresFrom+`
+		AND m.order_number <> ''
+		UNION
+		SELECT d.order_number
+		FROM quota_definition_goods_codes q
+		JOIN quota_definitions d ON q.quota_definition_id = d.id
+		JOIN nomenclatures n ON q.goods_code = n.goods_code
+		WHERE n.hierarchy_path @> $1::ltree AND n.start_date <= $2 AND (n.end_date IS NULL OR n.end_date >= $2)
+		AND d.start_date <= $2 AND (d.end_date IS NULL OR d.end_date >= $2)
+		ORDER BY 1
+	`, hierarchyPath, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quota order numbers: %v", err)
+	}
+	defer rows.Close()
+
+	orderNumbers := []string{}
+	for rows.Next() {
+		var orderNumber string
+		if err := rows.Scan(&orderNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan quota order number: %v", err)
+		}
+		orderNumbers = append(orderNumbers, orderNumber)
+	}
+
+	return orderNumbers, rows.Err()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Server holds the dependencies shared by all HTTP handlers
+type Server struct {
+	db *sql.DB
+}
+
+// NewServer creates a server backed by the given database connection
+func NewServer(db *sql.DB) *Server {
+	return &Server{db: db}
+}
+
+// Routes registers all API endpoints and returns the resulting handler
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /nomenclatures/{goods_code}", s.handleGetNomenclature)
+	mux.HandleFunc("GET /nomenclatures/{goods_code}/children", s.handleGetChildren)
+	mux.HandleFunc("GET /nomenclatures/{goods_code}/ancestors", s.handleGetAncestors)
+	mux.HandleFunc("GET /nomenclatures/{goods_code}/subtree", s.handleGetSubtree)
+	mux.HandleFunc("GET /duties/{goods_code}", s.handleGetDuties)
+	mux.HandleFunc("GET /documents/{goods_code}", s.handleGetDocuments)
+	mux.HandleFunc("GET /quotas/{order_number}", s.handleGetQuota)
+
+	return mux
+}
+
+// errorResponse is the JSON body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON encodes the value as JSON and writes it with the given status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeError writes an error message as JSON with the given status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// formatDate formats a date column in the same YYYY-MM-DD form it is stored in
+func formatDate(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+// parseAsOf reads the as_of query parameter: the date the returned nomenclature must be valid on,
+// equivalent to the SimDate parameter of the TARIC consultation. Defaults to today.
+func parseAsOf(r *http.Request) (time.Time, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+
+	asOf, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of date %q: expected YYYY-MM-DD", value)
+	}
+
+	return asOf, nil
+}
+
+// formatNullDate formats an optional date column, returning nil when it is not set
+func formatNullDate(date sql.NullTime) *string {
+	if !date.Valid {
+		return nil
+	}
+
+	formatted := formatDate(date.Time)
+	return &formatted
+}
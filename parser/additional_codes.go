@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AdditionalCodeEntry represents a single row from the TARIC additional codes export
+type AdditionalCodeEntry struct {
+	CodeType    string     // Additional code type (e.g. "C" anti-dumping, "7" Meursing, "X" excise)
+	Code        string     // 3-character additional code within its type
+	StartDate   time.Time  // Validity start date of the additional code
+	EndDate     *time.Time // Validity end date of the additional code (can be empty)
+	Language    string     // Language code of the description
+	Description string     // Description of the additional code, e.g. the company name for anti-dumping codes
+}
+
+// AdditionalCodesParser implements the Parser interface for additional code files
+type AdditionalCodesParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the additional codes export
+func (p *AdditionalCodesParser) Columns() []Column {
+	return []Column{
+		{Name: "Add. code type", Aliases: []string{"Additional code type"}, Optional: true},
+		{Name: "Add. code", Aliases: []string{"Additional code"}},
+		startDateColumn,
+		endDateColumn,
+		languageColumn,
+		descriptionColumn,
+	}
+}
+
+// MapRow converts an ExcelRow of the additional codes export to an AdditionalCodeEntry.
+func (p *AdditionalCodesParser) MapRow(rowData RowData) (AdditionalCodeEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return AdditionalCodeEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	entry := AdditionalCodeEntry{
+		CodeType:    strings.TrimSpace(row.Value("Add. code type")),
+		Code:        strings.TrimSpace(row.Value("Add. code")),
+		Language:    strings.TrimSpace(row.Value("Language")),
+		Description: strings.TrimSpace(row.Value("Description")),
+	}
+
+	// The code may be given together with its type, e.g. "C999"
+	if len(entry.Code) == 4 && (entry.CodeType == "" || entry.Code[:1] == entry.CodeType) {
+		entry.CodeType = entry.Code[:1]
+		entry.Code = entry.Code[1:]
+	}
+
+	if len(entry.CodeType) != 1 {
+		return AdditionalCodeEntry{}, fmt.Errorf("invalid additional code type %q", entry.CodeType)
+	}
+	if len(entry.Code) != 3 {
+		return AdditionalCodeEntry{}, fmt.Errorf("invalid additional code %q", entry.Code)
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return AdditionalCodeEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return AdditionalCodeEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for additional codes
+func (p *AdditionalCodesParser) ProcessEntry(entry *AdditionalCodeEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of additional code entries to the database
+func (p *AdditionalCodesParser) SaveEntries(ctx context.Context, db *sql.DB, entries []AdditionalCodeEntry) (int, error) {
+	return insertAdditionalCodeEntries(ctx, db, entries)
+}
+
+func insertAdditionalCodeEntries(ctx context.Context, db *sql.DB, entries []AdditionalCodeEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	codeStmt, err := tx.Prepare(`
+		INSERT INTO additional_codes (code_type, code, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code_type, code, start_date)
+		DO UPDATE SET end_date = $4, updated_at = NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare additional code statement: %v", err)
+	}
+	defer codeStmt.Close()
+
+	descStmt, err := tx.Prepare(`
+		INSERT INTO additional_code_descriptions (additional_code_id, language, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (additional_code_id, language)
+		DO UPDATE SET description = $3, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare description statement: %v", err)
+	}
+	defer descStmt.Close()
+
+	// Link measures imported before their additional code, starting within the validity period of the code
+	linkStmt, err := tx.Prepare(`
+		UPDATE measures SET additional_code_id = $1
+		WHERE additional_code = $2 AND start_date >= $3 AND ($4::date IS NULL OR start_date <= $4)
+			AND additional_code_id IS DISTINCT FROM $1
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure link statement: %v", err)
+	}
+	defer linkStmt.Close()
+
+	// Link measures once per validity period of an additional code in the batch
+	linkedPeriods := make(map[int]bool)
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		var codeID int
+		err = codeStmt.QueryRow(entry.CodeType, entry.Code, entry.StartDate, entry.EndDate).Scan(&codeID)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert additional code %s%s: %v", entry.CodeType, entry.Code, err)
+		}
+
+		if entry.Language != "" && entry.Description != "" {
+			if _, err := descStmt.Exec(codeID, entry.Language, entry.Description); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert description for additional code %s%s: %v", entry.CodeType, entry.Code, err)
+			}
+		}
+
+		fullCode := entry.CodeType + entry.Code
+		if !linkedPeriods[codeID] {
+			if _, err := linkStmt.Exec(codeID, fullCode, entry.StartDate, entry.EndDate); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to link measures to additional code %s: %v", fullCode, err)
+			}
+			linkedPeriods[codeID] = true
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
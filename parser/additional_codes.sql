@@ -0,0 +1,67 @@
+-- Table to store TARIC additional codes (e.g. C999 anti-dumping company codes, 7xxx Meursing codes, X excise codes)
+CREATE TABLE additional_codes (
+    id SERIAL PRIMARY KEY,
+    code_type CHAR(1) NOT NULL,
+    code VARCHAR(3) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(code_type, code, start_date)
+);
+
+-- Table to store localized additional code descriptions
+CREATE TABLE additional_code_descriptions (
+    id SERIAL PRIMARY KEY,
+    additional_code_id INTEGER NOT NULL REFERENCES additional_codes(id) ON DELETE CASCADE,
+    language CHAR(2) NOT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(additional_code_id, language)
+);
+
+CREATE INDEX idx_additional_code_descriptions_language ON additional_code_descriptions(language);
+
+-- Link measures to the additional codes they use
+ALTER TABLE measures ADD COLUMN additional_code_id INTEGER REFERENCES additional_codes(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_measures_additional_code_id ON measures(additional_code_id);
+
+-- Measures reference additional codes by their type and code (e.g. "C999"), resolve the link to the validity
+-- period of the code in which the measure starts when they are saved
+CREATE OR REPLACE FUNCTION link_measure_additional_code()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.additional_code_id = (
+        SELECT id FROM additional_codes
+        WHERE code_type || code = NEW.additional_code
+            AND start_date <= NEW.start_date AND (end_date IS NULL OR end_date >= NEW.start_date)
+        ORDER BY start_date DESC
+        LIMIT 1
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER link_measures_additional_code
+BEFORE INSERT OR UPDATE OF additional_code, start_date ON measures
+FOR EACH ROW EXECUTE FUNCTION link_measure_additional_code();
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_additional_codes_modtime
+BEFORE UPDATE ON additional_codes
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_additional_code_descriptions_modtime
+BEFORE UPDATE ON additional_code_descriptions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
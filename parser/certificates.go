@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertificateEntry represents a single row from the TARIC certificates export
+type CertificateEntry struct {
+	CertificateType string     // Certificate type (e.g. "Y" particular provisions, "C" import licences, "N" UN/EDIFACT documents)
+	Code            string     // 3-character certificate code within its type
+	StartDate       time.Time  // Validity start date of the certificate
+	EndDate         *time.Time // Validity end date of the certificate (can be empty)
+	Language        string     // Language code of the description
+	Description     string     // Description of the certificate or document
+}
+
+// CertificatesParser implements the Parser interface for certificate files
+type CertificatesParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the certificates export
+func (p *CertificatesParser) Columns() []Column {
+	return []Column{
+		{Name: "Certificate type", Optional: true},
+		{Name: "Certificate code", Aliases: []string{"Certificate"}},
+		startDateColumn,
+		endDateColumn,
+		languageColumn,
+		descriptionColumn,
+	}
+}
+
+// MapRow converts an ExcelRow of the certificates export to a CertificateEntry.
+func (p *CertificatesParser) MapRow(rowData RowData) (CertificateEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return CertificateEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	certificateType, code, err := splitCertificate(row.Value("Certificate type"), row.Value("Certificate code"))
+	if err != nil {
+		return CertificateEntry{}, err
+	}
+
+	entry := CertificateEntry{
+		CertificateType: certificateType,
+		Code:            code,
+		Language:        strings.TrimSpace(row.Value("Language")),
+		Description:     strings.TrimSpace(row.Value("Description")),
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return CertificateEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return CertificateEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	return entry, nil
+}
+
+// splitCertificate returns the type and code of a certificate. The code may be given
+// together with its type (e.g. "Y900"), in which case the type column can be empty.
+func splitCertificate(certificateType string, code string) (string, string, error) {
+	certificateType = strings.TrimSpace(certificateType)
+	code = strings.ReplaceAll(code, " ", "")
+
+	if len(code) == 4 && (certificateType == "" || code[:1] == certificateType) {
+		certificateType = code[:1]
+		code = code[1:]
+	}
+
+	if len(certificateType) != 1 {
+		return "", "", fmt.Errorf("invalid certificate type %q", certificateType)
+	}
+	if len(code) != 3 {
+		return "", "", fmt.Errorf("invalid certificate code %q", code)
+	}
+
+	return certificateType, code, nil
+}
+
+// ProcessEntry performs no additional processing for certificates
+func (p *CertificatesParser) ProcessEntry(entry *CertificateEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of certificate entries to the database
+func (p *CertificatesParser) SaveEntries(ctx context.Context, db *sql.DB, entries []CertificateEntry) (int, error) {
+	return insertCertificateEntries(ctx, db, entries)
+}
+
+func insertCertificateEntries(ctx context.Context, db *sql.DB, entries []CertificateEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	certificateStmt, err := tx.Prepare(`
+		INSERT INTO certificates (certificate_type, code, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (certificate_type, code, start_date)
+		DO UPDATE SET end_date = $4, updated_at = NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare certificate statement: %v", err)
+	}
+	defer certificateStmt.Close()
+
+	descStmt, err := tx.Prepare(`
+		INSERT INTO certificate_descriptions (certificate_id, language, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (certificate_id, language)
+		DO UPDATE SET description = $3, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare description statement: %v", err)
+	}
+	defer descStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		certificate := entry.CertificateType + entry.Code
+
+		var certificateID int
+		err = certificateStmt.QueryRow(entry.CertificateType, entry.Code, entry.StartDate, entry.EndDate).Scan(&certificateID)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert certificate %s: %v", certificate, err)
+		}
+
+		if entry.Language != "" && entry.Description != "" {
+			if _, err := descStmt.Exec(certificateID, entry.Language, entry.Description); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert description for certificate %s: %v", certificate, err)
+			}
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
@@ -0,0 +1,67 @@
+-- Table to store TARIC certificates and documents (e.g. Y900, C400, N853)
+CREATE TABLE certificates (
+    id SERIAL PRIMARY KEY,
+    certificate_type CHAR(1) NOT NULL,
+    code VARCHAR(3) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- A certificate can be closed and reopened, so every validity period is kept as a separate row
+    UNIQUE(certificate_type, code, start_date)
+);
+
+-- Table to store localized certificate descriptions
+CREATE TABLE certificate_descriptions (
+    id SERIAL PRIMARY KEY,
+    certificate_id INTEGER NOT NULL REFERENCES certificates(id) ON DELETE CASCADE,
+    language CHAR(2) NOT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(certificate_id, language)
+);
+
+-- Table to store conditions of measures, e.g. documents that have to be presented for a measure to apply
+CREATE TABLE measure_conditions (
+    id SERIAL PRIMARY KEY,
+    measure_id INTEGER NOT NULL REFERENCES measures(id) ON DELETE CASCADE,
+    condition_code VARCHAR(2) NOT NULL,
+    sequence_number SMALLINT NOT NULL,
+    certificate_type CHAR(1) NOT NULL DEFAULT '',
+    certificate_code VARCHAR(3) NOT NULL DEFAULT '',
+    action_code VARCHAR(2) NOT NULL DEFAULT '',
+    duty_amount NUMERIC(15, 3),
+    unit TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(measure_id, condition_code, sequence_number)
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_certificate_descriptions_language ON certificate_descriptions(language);
+CREATE INDEX idx_measure_conditions_measure_id ON measure_conditions(measure_id);
+CREATE INDEX idx_measure_conditions_certificate ON measure_conditions(certificate_type, certificate_code);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_certificates_modtime
+BEFORE UPDATE ON certificates
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_certificate_descriptions_modtime
+BEFORE UPDATE ON certificate_descriptions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_measure_conditions_modtime
+BEFORE UPDATE ON measure_conditions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// importCheckpoint is the last row of an import run whose batch, and every batch before it, is committed.
+// Resuming the run starts at the row after it.
+type importCheckpoint struct {
+	RunID  int
+	Source string // File of the row, e.g. "Nomenclature EN.xlsx"
+	Sheet  string // Sheet of the row, empty for CSV and XML files
+	Row    int    // Row number as shown in Excel, line number in a CSV file, or position in the import
+	Rows   int    // Rows of the import read up to and including the row, skipped when resuming
+}
+
+// newImportCheckpoint returns the checkpoint of an import run after the given row
+func newImportCheckpoint(runID int, row RowData, rowNumber int) importCheckpoint {
+	source, sheet, number := rowPosition(row, rowNumber)
+	return importCheckpoint{RunID: runID, Source: source, Sheet: sheet, Row: number, Rows: rowNumber}
+}
+
+// String describes where the import resumes, e.g. "after Nomenclature EN.xlsx, sheet Sheet1, row 1001"
+func (c importCheckpoint) String() string {
+	location := fmt.Sprintf("row %d", c.Row)
+	if c.Sheet != "" {
+		location = fmt.Sprintf("sheet %s, %s", c.Sheet, location)
+	}
+	if c.Source != "" {
+		location = fmt.Sprintf("%s, %s", c.Source, location)
+	}
+
+	return fmt.Sprintf("after %s (%d rows)", location, c.Rows)
+}
+
+// rowPosition returns the file, sheet and row number a row came from. Rows not read from a spreadsheet or
+// CSV file are numbered by their position in the import.
+func rowPosition(row RowData, rowNumber int) (source, sheet string, number int) {
+	switch row := row.(type) {
+	case ExcelRow:
+		if row.Row > 0 {
+			rowNumber = row.Row
+		}
+		return row.Source, row.Sheet, rowNumber
+	case TaricTransaction:
+		return row.Source, "", rowNumber
+	default:
+		return "", "", rowNumber
+	}
+}
+
+// saveCheckpoint records the checkpoint of the import run, replacing the one before it
+func (r *importRun) saveCheckpoint(db *sql.DB, checkpoint importCheckpoint) error {
+	_, err := db.Exec(`
+		INSERT INTO import_checkpoints (import_run_id, file_name, sheet, row_number, rows_committed)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (import_run_id)
+		DO UPDATE SET file_name = EXCLUDED.file_name, sheet = EXCLUDED.sheet, row_number = EXCLUDED.row_number,
+			rows_committed = EXCLUDED.rows_committed, updated_at = NOW()
+	`, r.ID, checkpoint.Source, checkpoint.Sheet, checkpoint.Row, checkpoint.Rows)
+	if err != nil {
+		return fmt.Errorf("failed to record checkpoint of import run %d: %v", r.ID, err)
+	}
+
+	return nil
+}
+
+// findResumeCheckpoint returns the checkpoint of the last run of the parser type on the same path, which must
+// have failed or been interrupted while reading the same files. A run which committed no batch resumes at the
+// first row, with a checkpoint of 0 rows.
+func findResumeCheckpoint(db *sql.DB, config ParserConfig, files []importFile) (importCheckpoint, error) {
+	var checkpoint importCheckpoint
+	var status string
+	err := db.QueryRow(`
+		SELECT id, status FROM import_runs
+		WHERE parser_type = $1 AND path = $2 AND status <> 'skipped'
+		ORDER BY id DESC
+		LIMIT 1
+	`, config.ParserType, config.FilePath).Scan(&checkpoint.RunID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkpoint, fmt.Errorf("no import run of %s to resume", config.FilePath)
+	}
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to find import run to resume: %v", err)
+	}
+	if status != importFailed && status != importInterrupted {
+		return checkpoint, fmt.Errorf("import run %d of %s is %s, only failed or interrupted runs can be resumed", checkpoint.RunID, config.FilePath, status)
+	}
+
+	// Rows are skipped by their position in the import, which only holds for the same files in the same order
+	rows, err := db.Query(`
+		SELECT file_name, sha256 FROM import_run_files
+		WHERE import_run_id = $1 AND NOT skipped
+		ORDER BY id
+	`, checkpoint.RunID)
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to find files of import run %d: %v", checkpoint.RunID, err)
+	}
+	defer rows.Close()
+
+	var readFiles []importFile
+	for _, file := range files {
+		if !config.SkipFiles[file.File] {
+			readFiles = append(readFiles, file)
+		}
+	}
+
+	i := 0
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return checkpoint, fmt.Errorf("failed to find files of import run %d: %v", checkpoint.RunID, err)
+		}
+		if i >= len(readFiles) || readFiles[i].File.Name() != name || readFiles[i].SHA256 != checksum {
+			return checkpoint, fmt.Errorf("files read by import run %d have changed, it cannot be resumed", checkpoint.RunID)
+		}
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return checkpoint, fmt.Errorf("failed to find files of import run %d: %v", checkpoint.RunID, err)
+	}
+	if i != len(readFiles) {
+		return checkpoint, fmt.Errorf("files read by import run %d have changed, it cannot be resumed", checkpoint.RunID)
+	}
+
+	err = db.QueryRow(`
+		SELECT file_name, sheet, row_number, rows_committed FROM import_checkpoints
+		WHERE import_run_id = $1
+	`, checkpoint.RunID).Scan(&checkpoint.Source, &checkpoint.Sheet, &checkpoint.Row, &checkpoint.Rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return checkpoint, fmt.Errorf("failed to find checkpoint of import run %d: %v", checkpoint.RunID, err)
+	}
+
+	return checkpoint, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column describes a column a parser reads by its header name
+type Column struct {
+	Name     string   // Header name in the EN export, also used to read the value with ExcelRow.Value
+	Aliases  []string // Header names of the same column in exports of other languages
+	Optional bool     // Optional columns may be missing from the file, their value is then empty
+}
+
+// ColumnParser is implemented by parsers that read cells by header name instead of position.
+// The header row of every file is validated against the declared columns before any row is read.
+type ColumnParser interface {
+	Columns() []Column
+}
+
+// Columns shared by most TARIC exports
+var (
+	goodsCodeColumn   = Column{Name: "Goods code", Aliases: []string{"Prekių kodas"}}
+	startDateColumn   = Column{Name: "Start date", Aliases: []string{"Pradžios data"}}
+	endDateColumn     = Column{Name: "End date", Aliases: []string{"Pabaigos data"}}
+	languageColumn    = Column{Name: "Language", Aliases: []string{"Kalba"}}
+	descriptionColumn = Column{Name: "Description", Aliases: []string{"Aprašymas"}}
+)
+
+// optional returns a copy of the column that may be missing from the file
+func (c Column) optional() Column {
+	c.Optional = true
+	return c
+}
+
+// normalizeHeader makes header names comparable regardless of case, dots, underscores and spacing,
+// so "Hier. Pos.", "hier pos" and "HIER_POS" are the same column
+func normalizeHeader(header string) string {
+	header = strings.ToLower(header)
+	header = strings.NewReplacer(".", " ", "_", " ").Replace(header)
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// mapColumns matches the header row of a file against the declared columns and returns the index of
+// every column found, keyed by column name. Required columns missing from the headers and headers
+// matching no declared column are reported together, since either means the file layout has changed.
+func mapColumns(headers []string, columns []Column) (map[string]int, error) {
+	names := make(map[string]string)
+	for _, column := range columns {
+		names[normalizeHeader(column.Name)] = column.Name
+		for _, alias := range column.Aliases {
+			names[normalizeHeader(alias)] = column.Name
+		}
+	}
+
+	indexes := make(map[string]int)
+	var unexpected []string
+	for i, header := range headers {
+		normalized := normalizeHeader(header)
+		if normalized == "" {
+			continue
+		}
+
+		name, ok := names[normalized]
+		if !ok {
+			unexpected = append(unexpected, fmt.Sprintf("%q", header))
+			continue
+		}
+		if _, duplicate := indexes[name]; duplicate {
+			unexpected = append(unexpected, fmt.Sprintf("%q (duplicate of %q)", header, name))
+			continue
+		}
+		indexes[name] = i
+	}
+
+	var missing []string
+	for _, column := range columns {
+		if _, ok := indexes[column.Name]; !ok && !column.Optional {
+			missing = append(missing, fmt.Sprintf("%q", column.Name))
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing columns "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, "unexpected columns "+strings.Join(unexpected, ", "))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return indexes, nil
+}
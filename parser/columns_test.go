@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapColumns(t *testing.T) {
+	columns := []Column{
+		goodsCodeColumn,
+		{Name: "Hier. Pos.", Aliases: []string{"Hier. poz."}},
+		{Name: "Comment", Optional: true},
+	}
+
+	tests := []struct {
+		name     string
+		headers  []string
+		expected map[string]int
+		errors   []string
+	}{
+		{"Declared order", []string{"Goods code", "Hier. Pos.", "Comment"}, map[string]int{"Goods code": 0, "Hier. Pos.": 1, "Comment": 2}, nil},
+		{"Reordered", []string{"Hier. Pos.", "Goods code"}, map[string]int{"Goods code": 1, "Hier. Pos.": 0}, nil},
+		{"Normalized", []string{" GOODS_CODE ", "hier pos", "", ""}, map[string]int{"Goods code": 0, "Hier. Pos.": 1}, nil},
+		{"Alias", []string{"Prekių kodas", "Hier. poz."}, map[string]int{"Goods code": 0, "Hier. Pos.": 1}, nil},
+		{"Missing", []string{"Goods code"}, nil, []string{`missing columns "Hier. Pos."`}},
+		{"Unexpected", []string{"Goods code", "Hier. Pos.", "Indent"}, nil, []string{`unexpected columns "Indent"`}},
+		{"Duplicate", []string{"Goods code", "Hier. Pos.", "Prekių kodas"}, nil, []string{`"Prekių kodas" (duplicate of "Goods code")`}},
+		{"Missing and unexpected", []string{"Code", "Hier. Pos."}, nil, []string{`missing columns "Goods code"`, `unexpected columns "Code"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			indexes, err := mapColumns(tc.headers, columns)
+
+			if len(tc.errors) > 0 {
+				if err == nil {
+					t.Fatalf("mapColumns(%q) returned no error, expected %v", tc.headers, tc.errors)
+				}
+				for _, expected := range tc.errors {
+					if !strings.Contains(err.Error(), expected) {
+						t.Errorf("mapColumns(%q) error %q does not contain %q", tc.headers, err, expected)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("mapColumns(%q) returned error: %v", tc.headers, err)
+			}
+			if len(indexes) != len(tc.expected) {
+				t.Errorf("mapColumns(%q) = %v, expected %v", tc.headers, indexes, tc.expected)
+			}
+			for name, index := range tc.expected {
+				if indexes[name] != index {
+					t.Errorf("mapColumns(%q) mapped %q to %d, expected %d", tc.headers, name, indexes[name], index)
+				}
+			}
+		})
+	}
+}
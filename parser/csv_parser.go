@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// CSVOptions configures how CSV and TSV files are read
+type CSVOptions struct {
+	Delimiter  rune   // Field delimiter, a comma by default or a tab for TSV files
+	Quote      rune   // Quote character of fields, '"' by default, e.g. '\'' for single quoted files
+	LazyQuotes bool   // Allow quotes in unquoted fields and non-doubled quotes in quoted fields
+	Encoding   string // Character encoding, e.g. "windows-1257" for Baltic exports, UTF-8 if empty
+}
+
+// BaseCSVParser provides common CSV file reading functionality.
+// The first record of every file is the header row, and rows are sent as ExcelRow
+// so parsers can map them the same way as Excel rows.
+type BaseCSVParser struct{}
+
+// ReadRows reads a CSV file, or all CSV and TSV files of a directory.
+// When the parser declares its columns, the headers of every file are validated before any row is read.
+func (p *BaseCSVParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	csvFiles, err := inputFiles(config, isCSVFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := csvEncoding(config.CSV.Encoding); err != nil {
+		return nil, err
+	}
+
+	if len(config.Columns) > 0 {
+		if err := validateCSVHeaders(csvFiles, config); err != nil {
+			return nil, err
+		}
+	}
+
+	rowsChan := make(chan RowData)
+
+	go processCSVFiles(ctx, csvFiles, config, rowsChan)
+
+	return rowsChan, nil
+}
+
+// isCSVFile checks if the file has a CSV or TSV extension
+func isCSVFile(path string) bool {
+	format := fileFormat(path)
+	return format == formatCSV || format == formatTSV
+}
+
+// parseDelimiter parses a delimiter given on the command line, accepting "\t" and "tab" for a tab
+func parseDelimiter(value string) (rune, error) {
+	if value == `\t` || strings.EqualFold(value, "tab") {
+		return '\t', nil
+	}
+
+	delimiter, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || delimiter == utf8.RuneError || delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q: expected a single character", value)
+	}
+
+	return delimiter, nil
+}
+
+// parseQuote parses a quote character given on the command line. The quote character of encoding/csv is
+// fixed, so it has to be a single ASCII character which csvReader can swap with '"'.
+func parseQuote(value string) (rune, error) {
+	if len(value) != 1 || value[0] >= utf8.RuneSelf || value == "\r" || value == "\n" {
+		return 0, fmt.Errorf("invalid quote %q: expected a single ASCII character", value)
+	}
+
+	return rune(value[0]), nil
+}
+
+// csvEncoding resolves an encoding name, e.g. "windows-1257" or "iso-8859-13", defaulting to UTF-8
+func csvEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q: %v", name, err)
+	}
+
+	return enc, nil
+}
+
+// csvReader reads records of a CSV file quoted with another character than '"'. The input swaps the quote
+// character with '"' before encoding/csv reads it, and Read swaps them back in the fields.
+type csvReader struct {
+	*csv.Reader
+	quote byte // Quote character of the file, 0 for '"'
+}
+
+// Read reads a record, restoring the quote characters swapped in the input
+func (r *csvReader) Read() ([]string, error) {
+	record, err := r.Reader.Read()
+	if r.quote == 0 {
+		return record, err
+	}
+
+	for i, field := range record {
+		record[i] = strings.Map(r.swap, field)
+	}
+	return record, err
+}
+
+func (r *csvReader) swap(c rune) rune {
+	switch c {
+	case '"':
+		return rune(r.quote)
+	case rune(r.quote):
+		return '"'
+	}
+	return c
+}
+
+// quoteSwapper is a transformer swapping an ASCII quote character with '"'. Bytes of ASCII characters do not
+// occur in other UTF-8 sequences, so the input is swapped byte by byte.
+type quoteSwapper struct {
+	quote byte
+	transform.NopResetter
+}
+
+func (t quoteSwapper) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	n := copy(dst, src)
+	for i, c := range dst[:n] {
+		switch c {
+		case '"':
+			dst[i] = t.quote
+		case t.quote:
+			dst[i] = '"'
+		}
+	}
+
+	if n < len(src) {
+		return n, n, transform.ErrShortDst
+	}
+	return n, n, nil
+}
+
+// openCSV opens a CSV file for reading. A byte order mark, if present, selects the UTF-8 or UTF-16
+// encoding and is removed, otherwise the configured encoding is used.
+func openCSV(file inputFile, config ParserConfig) (*csvReader, io.Closer, error) {
+	enc, err := csvEncoding(config.CSV.Encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input, err := file.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open CSV file: %v", err)
+	}
+
+	decoder := transform.Transformer(unicode.BOMOverride(enc.NewDecoder()))
+	var quote byte
+	if config.CSV.Quote != 0 && config.CSV.Quote != '"' {
+		quote = byte(config.CSV.Quote)
+		decoder = transform.Chain(decoder, quoteSwapper{quote: quote})
+	}
+
+	reader := &csvReader{Reader: csv.NewReader(transform.NewReader(input, decoder)), quote: quote}
+	reader.Comma = config.CSV.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = ','
+		if config.Format == formatTSV || fileFormat(file.Name()) == formatTSV {
+			reader.Comma = '\t'
+		}
+	}
+	reader.LazyQuotes = config.CSV.LazyQuotes
+	reader.FieldsPerRecord = -1 // Rows may have fewer or more fields than the header
+
+	return reader, input, nil
+}
+
+// validateCSVHeaders checks the header row of every file against the declared columns,
+// reporting the problems of all files at once
+func validateCSVHeaders(csvFiles []inputFile, config ParserConfig) error {
+	var problems []string
+	for _, file := range csvFiles {
+		headers, err := readCSVHeaders(file, config)
+		if err == nil {
+			_, err = mapColumns(headers, config.Columns)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file.Name(), err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid headers:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// readCSVHeaders reads the header row of a CSV file
+func readCSVHeaders(file inputFile, config ParserConfig) ([]string, error) {
+	reader, closer, err := openCSV(file, config)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	headers, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("no header row found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %v", err)
+	}
+
+	return trimHeaders(headers), nil
+}
+
+// processCSVFiles reads the CSV files, up to config.Workers at a time, sends their rows to the channel in the
+// order of the files and closes it
+func processCSVFiles(ctx context.Context, csvFiles []inputFile, config ParserConfig, rowsChan chan<- RowData) {
+	defer close(rowsChan)
+
+	if len(csvFiles) == 0 {
+		log.Printf("No CSV files found")
+		return
+	}
+
+	fileNumbers := make(map[inputFile]int, len(csvFiles))
+	for i, file := range csvFiles {
+		fileNumbers[file] = i + 1
+	}
+
+	readFiles(ctx, csvFiles, config, rowsChan, func(file inputFile, fileChan chan<- RowData) {
+		if len(csvFiles) > 1 {
+			log.Printf("Processing CSV file %d/%d: %s", fileNumbers[file], len(csvFiles), file.Name())
+		}
+		processCSVFile(ctx, file, config, fileChan)
+	})
+}
+
+// processCSVFile reads a single CSV file and sends its rows to the channel
+func processCSVFile(ctx context.Context, file inputFile, config ParserConfig, rowsChan chan<- RowData) {
+	filename := file.Name()
+
+	reader, closer, err := openCSV(file, config)
+	if err != nil {
+		log.Printf("Failed to open CSV file %s: %v", filename, err)
+		return
+	}
+	defer closer.Close()
+
+	var headers []string
+	var columnIndexes map[string]int
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				log.Printf("Failed to read CSV file %s: %v", filename, err)
+				return
+			}
+			// A malformed record is rejected, the reader continues with the next line
+			excelRow := ExcelRow{
+				Headers: headers,
+				Width:   len(headers),
+				Source:  filename,
+				Row:     parseErr.StartLine,
+				ReadErr: err,
+			}
+			if !sendRow(ctx, rowsChan, excelRow) {
+				return
+			}
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		// The first record holds the headers
+		if headers == nil {
+			headers = trimHeaders(record)
+			if len(config.Columns) > 0 {
+				columnIndexes, err = mapColumns(headers, config.Columns)
+				if err != nil {
+					log.Printf("Invalid headers in %s: %v", filename, err)
+					return
+				}
+			}
+			continue
+		}
+
+		// Skip lines without values, the Excel reader does not return empty rows either
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		cells := make([]string, len(headers))
+		copy(cells, record)
+		if len(record) > len(cells) {
+			cells = record
+		}
+
+		excelRow := ExcelRow{
+			Cells:   cells,
+			Headers: headers,
+			Width:   len(headers),
+			Source:  filename,
+			Row:     line,
+			columns: columnIndexes,
+		}
+		if !sendRow(ctx, rowsChan, excelRow) {
+			return
+		}
+	}
+}
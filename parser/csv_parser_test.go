@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessCSVFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		config   ParserConfig
+		expected [][]string
+		rows     []int
+	}{
+		{
+			"UTF-8 with BOM",
+			[]byte("\xef\xbb\xbfGoods code,Description\n0101000000 80,Horses\n"),
+			ParserConfig{},
+			[][]string{{"0101000000 80", "Horses"}},
+			[]int{2},
+		},
+		{
+			"Windows-1257 with semicolons",
+			[]byte("Goods code;Description\r\n0101000000 80;\"Arkliai; \xfeirgai\"\r\n"),
+			ParserConfig{CSV: CSVOptions{Delimiter: ';', Encoding: "windows-1257"}},
+			[][]string{{"0101000000 80", "Arkliai; žirgai"}},
+			[]int{2},
+		},
+		{
+			"TSV with short, long and multiline rows",
+			[]byte("Goods code\tDescription\n0101000000 80\n\n0102000000 80\t\"Live\nbovine\"\n0103000000 80\tSwine\textra\n"),
+			ParserConfig{Format: formatTSV},
+			[][]string{{"0101000000 80", ""}, {"0102000000 80", "Live\nbovine"}, {"0103000000 80", "Swine", "extra"}},
+			[]int{2, 4, 6},
+		},
+		{
+			"Single quotes with double quotes in fields",
+			[]byte("Goods code;Description\n'0101000000 80';'Horses; \"live\" ''ponies'''\n"),
+			ParserConfig{CSV: CSVOptions{Delimiter: ';', Quote: '\''}},
+			[][]string{{"0101000000 80", "Horses; \"live\" 'ponies'"}},
+			[]int{2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "input.csv")
+			if err := os.WriteFile(filePath, tc.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.config.Columns = []Column{goodsCodeColumn, descriptionColumn}
+			rowsChan := make(chan RowData)
+			go func() {
+				processCSVFile(context.Background(), inputFile{Path: filePath}, tc.config, rowsChan)
+				close(rowsChan)
+			}()
+
+			var cells [][]string
+			var rows []int
+			for rowData := range rowsChan {
+				row := rowData.(ExcelRow)
+				cells = append(cells, row.Cells)
+				rows = append(rows, row.Row)
+
+				if row.Value("Goods code") != row.Cells[0] {
+					t.Errorf("Value(%q) = %q, expected %q", "Goods code", row.Value("Goods code"), row.Cells[0])
+				}
+			}
+
+			if !reflect.DeepEqual(cells, tc.expected) {
+				t.Errorf("processCSVFile() cells = %q, expected %q", cells, tc.expected)
+			}
+			if !reflect.DeepEqual(rows, tc.rows) {
+				t.Errorf("processCSVFile() rows = %v, expected %v", rows, tc.rows)
+			}
+		})
+	}
+}
+
+func TestProcessCSVFileMalformedRecord(t *testing.T) {
+	// A bare quote in the first field fails the record, the read continues with the next line
+	content := []byte("Goods code,Description\n0101000000 80,Horses\n01\"02000000 80,Bovine\n0103000000 80,Swine\n")
+	filePath := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(filePath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := ParserConfig{Columns: []Column{goodsCodeColumn, descriptionColumn}}
+	rowsChan := make(chan RowData)
+	go func() {
+		processCSVFile(context.Background(), inputFile{Path: filePath}, config, rowsChan)
+		close(rowsChan)
+	}()
+
+	var codes []string
+	var failedRows []int
+	for rowData := range rowsChan {
+		row := rowData.(ExcelRow)
+		if row.ReadErr != nil {
+			failedRows = append(failedRows, row.Row)
+			continue
+		}
+		codes = append(codes, row.Value("Goods code"))
+	}
+
+	if expected := []string{"0101000000 80", "0103000000 80"}; !reflect.DeepEqual(codes, expected) {
+		t.Errorf("processCSVFile() goods codes = %q, expected %q", codes, expected)
+	}
+	if expected := []int{3}; !reflect.DeepEqual(failedRows, expected) {
+		t.Errorf("processCSVFile() failed rows = %v, expected %v", failedRows, expected)
+	}
+
+	// The failed record is rejected at the read stage without mapping it
+	mapped := mapRow(nil, mappedRow{Row: ExcelRow{Source: "input.csv", Row: 3, ReadErr: errors.New("bare quote")}, RowNumber: 2})
+	if mapped.Stage != stageRead || mapped.Err == nil {
+		t.Errorf("mapRow() stage = %q, err = %v, expected stage %q with an error", mapped.Stage, mapped.Err, stageRead)
+	}
+}
+
+func TestParseQuote(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected rune
+		valid    bool
+	}{
+		{"'", '\'', true},
+		{`"`, '"', true},
+		{"|", '|', true},
+		{"''", 0, false},
+		{"„", 0, false},
+		{"\n", 0, false},
+	}
+
+	for _, tc := range tests {
+		quote, err := parseQuote(tc.value)
+		if tc.valid && (err != nil || quote != tc.expected) {
+			t.Errorf("parseQuote(%q) = %q, %v, expected %q", tc.value, quote, err, tc.expected)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("parseQuote(%q) = %q, expected an error", tc.value, quote)
+		}
+	}
+}
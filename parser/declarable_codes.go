@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -20,41 +22,46 @@ type DeclarableCodesEntry struct {
 
 // NomenclatureParser implements the Parser interface for nomenclature files
 type DeclarableCodesParser struct {
-    BaseExcelParser // Embed the BaseExcelParser to inherit ReadRows
+    BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
 }
 
-func (p *DeclarableCodesParser) MapRow(rowData RowData) (interface{}, error) {
+// Columns returns the columns of the declarable codes export
+func (p *DeclarableCodesParser) Columns() []Column {
+    return []Column{
+        goodsCodeColumn,
+        startDateColumn,
+        {Name: "Decl. start date", Aliases: []string{"Declarable start date"}},
+        {Name: "Is leaf", Aliases: []string{"Declarable"}},
+    }
+}
+
+func (p *DeclarableCodesParser) MapRow(rowData RowData) (DeclarableCodesEntry, error) {
 	row, ok := rowData.(ExcelRow)
     if !ok {
-        return nil, fmt.Errorf("expected ExcelRow, got %T", rowData)
-    }
-    
-    cells := row.Cells
-    if len(cells) < 4 {
-        return nil, fmt.Errorf("insufficient columns: need at least 4 columns")
+        return DeclarableCodesEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
     }
 
 	entry := DeclarableCodesEntry{}
-    entry.GoodsCode = cells[0]
+    entry.GoodsCode = row.Value("Goods code")
 
-    startDate, err := time.Parse("2006-01-02", cells[1])
+    startDate, err := time.Parse("2006-01-02", row.Value("Start date"))
     if err != nil {
-        return nil, fmt.Errorf("invalid start date: %v", err)
+        return DeclarableCodesEntry{}, fmt.Errorf("invalid start date: %v", err)
     }
 
     entry.StartDate = startDate
 
-    declStartDate, err := time.Parse("2006-01-02", cells[2])
+    declStartDate, err := time.Parse("2006-01-02", row.Value("Decl. start date"))
     if err != nil {	
-        return nil, fmt.Errorf("invalid declarable start date: %v", err)
+        return DeclarableCodesEntry{}, fmt.Errorf("invalid declarable start date: %v", err)
     }
 
     entry.DeclStartDate = declStartDate
 
-	isLeaf, err := strconv.ParseBool(cells[3])
+	isLeaf, err := strconv.ParseBool(row.Value("Is leaf"))
 
 	if err != nil {
-		return nil, fmt.Errorf("invalid is leaf: %v", err)
+		return DeclarableCodesEntry{}, fmt.Errorf("invalid is leaf: %v", err)
 	}
 
 	entry.Is_Leaf = isLeaf
@@ -62,31 +69,17 @@ func (p *DeclarableCodesParser) MapRow(rowData RowData) (interface{}, error) {
 	return entry, nil
 }
 
-func (p *DeclarableCodesParser) ProcessEntry(entry *interface{}) error {
+func (p *DeclarableCodesParser) ProcessEntry(entry *DeclarableCodesEntry) error {
 	// No processing needed for Declarable codes parser
 	return nil
 }
 
-func (p *DeclarableCodesParser) SaveEntries(db *sql.DB, entriesInterface []interface{}) (int, error) {
-	// Convert generic entries to NomenclatureEntry
-    entries := make([]DeclarableCodesEntry, len(entriesInterface))
-    for i, e := range entriesInterface {
-        switch entry := e.(type) {
-        case DeclarableCodesEntry:
-            entries[i] = entry
-        case *DeclarableCodesEntry:
-            entries[i] = *entry
-        default:
-            return 0, fmt.Errorf("invalid entry type at index %d: %T", i, e)
-        }
-    }
-    
-    // Use the existing insertEntries function
-    return insertDeclarableEntries(db, entries)
+func (p *DeclarableCodesParser) SaveEntries(ctx context.Context, db *sql.DB, entries []DeclarableCodesEntry) (int, error) {
+    return insertDeclarableEntries(ctx, db, entries)
 }
 
-func insertDeclarableEntries(db *sql.DB, entries []DeclarableCodesEntry) (int, error) {
-    tx, err := db.Begin()
+func insertDeclarableEntries(ctx context.Context, db *sql.DB, entries []DeclarableCodesEntry) (int, error) {
+    tx, err := db.BeginTx(ctx, nil)
     if err != nil {
         return 0, fmt.Errorf("failed to begin transaction: %v", err)
     }
@@ -105,7 +98,7 @@ func insertDeclarableEntries(db *sql.DB, entries []DeclarableCodesEntry) (int, e
 	// Query existing nomenclature entries for these goods codes
     // Use a proper placeholder for each item in the list
     query := fmt.Sprintf(`
-        SELECT id, goods_code FROM nomenclatures 
+        SELECT id, goods_code, start_date FROM nomenclatures 
         WHERE goods_code = ANY($1)
     `)
     
@@ -116,16 +109,18 @@ func insertDeclarableEntries(db *sql.DB, entries []DeclarableCodesEntry) (int, e
     }
     defer rows.Close()
 
-	// Create a map of goods_code to ID for quick lookup
+	// Create a map of goods_code and validity start date to ID for quick lookup,
+    // as every validity period of a goods code is stored as a separate nomenclature
     existingCodes := make(map[string]int)
     for rows.Next() {
         var id int
         var code string
-        if err := rows.Scan(&id, &code); err != nil {
+        var startDate time.Time
+        if err := rows.Scan(&id, &code, &startDate); err != nil {
             tx.Rollback()
             return 0, fmt.Errorf("failed to scan row: %v", err)
         }
-        existingCodes[code] = id
+        existingCodes[nomenclaturePeriodKey(code, startDate)] = id
     }
 
     // Prepare statements for both table
@@ -150,9 +145,9 @@ func insertDeclarableEntries(db *sql.DB, entries []DeclarableCodesEntry) (int, e
     // Process entries
     for _, entry := range entries {
         // Get the nomenclature ID from the map
-        nomenclatureID, exists := existingCodes[entry.GoodsCode]
+        nomenclatureID, exists := existingCodes[nomenclaturePeriodKey(entry.GoodsCode, entry.StartDate)]
         if !exists {
-            log.Printf("no nomenclature found for goods code: %s", entry.GoodsCode)
+            log.Printf("no nomenclature found for goods code: %s starting %s", entry.GoodsCode, entry.StartDate.Format("2006-01-02"))
             continue
         }
         
@@ -181,5 +176,31 @@ func insertDeclarableEntries(db *sql.DB, entries []DeclarableCodesEntry) (int, e
     return successCount, nil
 }
 
+// nomenclaturePeriodKey identifies a single validity period of a goods code
+func nomenclaturePeriodKey(goodsCode string, startDate time.Time) string {
+    return goodsCode + "|" + startDate.Format("2006-01-02")
+}
 
+// Validate checks that only goods lines, with suffix 80, are declarable and that no declarable period starts
+// before the period of its goods code
+func (p *DeclarableCodesParser) Validate(entries []DeclarableCodesEntry) []ValidationProblem {
+    var problems []ValidationProblem
+    for i, entry := range entries {
+        code, err := normalizeGoodsCode(entry.GoodsCode)
+        if err != nil {
+            problems = append(problems, ValidationProblem{Index: i, Message: err.Error()})
+            continue
+        }
+        if entry.Is_Leaf && !strings.HasSuffix(code, " 80") {
+            problems = append(problems, ValidationProblem{Index: i,
+                Message: fmt.Sprintf("goods code %s is declarable, but only codes with suffix 80 can be declared", code)})
+        }
+        if entry.DeclStartDate.Before(entry.StartDate) {
+            problems = append(problems, ValidationProblem{Index: i,
+                Message: fmt.Sprintf("goods code %s is declarable from %s, before it starts on %s",
+                    code, entry.DeclStartDate.Format("2006-01-02"), entry.StartDate.Format("2006-01-02"))})
+        }
+    }
 
+    return problems
+}
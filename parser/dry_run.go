@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// maxReportedIssues limits the errors and problems listed by the dry run report, the rest are counted
+const maxReportedIssues = 50
+
+// DryRunReport is the outcome of reading, mapping and validating the rows of an import without saving them
+type DryRunReport struct {
+	Rows        int      // Rows read
+	Entries     int      // Rows mapped and processed
+	Errors      []string // Rows failing to map or process, with their location
+	Problems    []string // Invariants not holding across the entries, with the location of the row
+	Interrupted bool     // The dry run was interrupted before every row was read, and the entries were not validated
+}
+
+// dryRun reads, maps and processes every row, then validates the entries if the parser is a Validator.
+// Nothing is written to the database; rejected rows are written to rejects, if it is not nil.
+// Once ctx is cancelled no more rows are read, and the report covers the rows read before.
+func dryRun(ctx context.Context, parser AnyParser, config ParserConfig, rejects *RejectsFile) (DryRunReport, error) {
+	var report DryRunReport
+
+	// Reading stops when ctx is cancelled, or when a rejected row cannot be written
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+
+	rowsChan, err := parser.ReadRows(readCtx, config)
+	if err != nil {
+		return report, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	// Entries are kept in memory with their rows, as invariants span the whole file
+	var entries []interface{}
+	var rows []RowData
+	var rowNumbers []int
+
+	mappedChan := mapRows(parser, rowsChan, config.Workers, 0)
+	for mapped := range mappedChan {
+		report.Rows++
+
+		if mapped.Err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", rowLocation(mapped.Row, mapped.RowNumber), mapped.Err))
+			if rejects != nil {
+				if err := rejects.Write(newRejectedRow(mapped.Row, mapped.RowNumber, mapped.Stage, mapped.Err)); err != nil {
+					cancelRead()
+					drainMappedRows(mappedChan)
+					return report, err
+				}
+			}
+			continue
+		}
+
+		entries = append(entries, mapped.Entry)
+		rows = append(rows, mapped.Row)
+		rowNumbers = append(rowNumbers, mapped.RowNumber)
+	}
+	report.Entries = len(entries)
+
+	// Invariants across rows do not hold for part of a file
+	if ctx.Err() != nil {
+		report.Interrupted = true
+		return report, nil
+	}
+
+	if validator, ok := parser.(anyValidator); ok {
+		for _, problem := range validator.Validate(entries) {
+			location := "entry"
+			if problem.Index >= 0 && problem.Index < len(rows) {
+				location = rowLocation(rows[problem.Index], rowNumbers[problem.Index])
+			}
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %s", location, problem.Message))
+		}
+	} else {
+		log.Printf("Parser %s has no validation across rows, only mapping and processing are checked", config.ParserType)
+	}
+
+	return report, nil
+}
+
+// OK reports whether every row was mapped and processed and no invariant was broken
+func (r DryRunReport) OK() bool {
+	return !r.Interrupted && len(r.Errors) == 0 && len(r.Problems) == 0
+}
+
+// Print prints the report, listing the first errors and problems
+func (r DryRunReport) Print() {
+	fmt.Println("\n*** Dry Run Report ***")
+	fmt.Printf("Rows read: %d\n", r.Rows)
+	fmt.Printf("Entries mapped: %d\n", r.Entries)
+
+	printIssues("Row errors", r.Errors)
+	printIssues("Validation problems", r.Problems)
+
+	if r.Interrupted {
+		fmt.Println("Dry run interrupted, only the rows read before were checked and no invariants were validated")
+	} else if r.OK() {
+		fmt.Println("No problems found, nothing was written to the database")
+	} else {
+		fmt.Println("Problems found, nothing was written to the database")
+	}
+}
+
+func printIssues(title string, issues []string) {
+	fmt.Printf("%s: %d\n", title, len(issues))
+	for i, issue := range issues {
+		if i == maxReportedIssues {
+			fmt.Printf("  ... and %d more\n", len(issues)-maxReportedIssues)
+			break
+		}
+		fmt.Printf("  %s\n", issue)
+	}
+}
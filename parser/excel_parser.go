@@ -1,179 +1,327 @@
 package main
 
 import (
+    "context"
     "fmt"
-    "io/fs"
+    "io"
     "log"
-    "muj/utils"
-    "os"
+    "path"
     "path/filepath"
     "strings"
 
     "github.com/thedatashed/xlsxreader"
 )
 
-// ExcelRow contains data from an Excel row
+// ExcelRow contains data from an Excel row, or a CSV record read by BaseCSVParser
 type ExcelRow struct {
-    Cells  []string
-    Source string // Added to track which file the row came from
+    Cells   []string
+    Headers []string // Header row of the file the row came from
+    Width   int      // Number of columns in the header row, Cells has at least this many entries
+    Source  string   // Added to track which file the row came from
+    Sheet   string   // Sheet the row came from, empty for CSV files
+    Row     int      // Row number in the sheet as shown in Excel, or line number in a CSV file
+    ReadErr error    // Error reading the row, e.g. a malformed CSV record, which is rejected without mapping it
+
+    columns map[string]int // Index of each declared column by name, nil if the parser declares no columns
+}
+
+// Value returns the cell of the named column, or an empty string if the column is not in the file
+func (r ExcelRow) Value(name string) string {
+    index, ok := r.columns[name]
+    if !ok || index >= len(r.Cells) {
+        return ""
+    }
+
+    return r.Cells[index]
+}
+
+// Location describes where the row came from, to be used in error messages
+func (r ExcelRow) Location() string {
+    switch {
+    case r.Row == 0:
+        return r.Source
+    case r.Sheet == "":
+        return fmt.Sprintf("%s, row %d", r.Source, r.Row)
+    default:
+        return fmt.Sprintf("%s, sheet %q, row %d", r.Source, r.Sheet, r.Row)
+    }
 }
 
 // BaseExcelParser provides common Excel file reading functionality
 type BaseExcelParser struct{}
 
-// ReadRows implements the common Excel file reading logic
-func (p *BaseExcelParser) ReadRows(config ParserConfig) (<-chan RowData, error) {
-    if config.FilePath == "" {
-        return nil, fmt.Errorf("file path is required for Excel parser")
+// ReadRows implements the common Excel file reading logic.
+// The selected sheets of every file and, when the parser declares its columns, their headers are
+// validated before any row is read, so a changed export layout fails the import up front.
+func (p *BaseExcelParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+    excelFiles, err := inputFiles(config, isExcelFile)
+    if err != nil {
+        return nil, err
     }
 
-    // Get absolute path
-    absPath := utils.GetAbsolutePath(config.FilePath)
-    
-    // Check if path is a file or directory
-    fileInfo, err := os.Stat(absPath)
-    if err != nil {
-        return nil, fmt.Errorf("failed to access path: %v", err)
+    if err := validateHeaders(excelFiles, config); err != nil {
+        return nil, err
     }
 
     rowsChan := make(chan RowData)
 
-    // Handle based on whether it's a file or directory
-    if fileInfo.IsDir() {
-        // It's a directory - process all Excel files
-        go processDirectory(absPath, rowsChan)
-    } else {
-        // It's a file - process the single file
-        go processFile(absPath, rowsChan)
-    }
+    go processFiles(ctx, excelFiles, config, rowsChan)
 
     return rowsChan, nil
 }
 
-// processDirectory reads all Excel files from a directory and processes them
-func processDirectory(dirPath string, rowsChan chan<- RowData) {
-    // Remove the defer close here, since we'll handle closing differently
-    // depending on whether we find files or not
-    
-    // Get all Excel files in the directory
-    var excelFiles []string
-    err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+// validateHeaders checks that every file has the selected sheets and that their header rows
+// match the declared columns, reporting the problems of all files at once
+func validateHeaders(excelFiles []inputFile, config ParserConfig) error {
+    var problems []string
+    for _, file := range excelFiles {
+        for _, problem := range validateFileHeaders(file, config) {
+            problems = append(problems, fmt.Sprintf("%s: %s", file.Name(), problem))
+        }
+    }
+
+    if len(problems) > 0 {
+        return fmt.Errorf("invalid headers:\n%s", strings.Join(problems, "\n"))
+    }
+
+    return nil
+}
+
+// validateFileHeaders returns the problems found in the selected sheets of a single file
+func validateFileHeaders(file inputFile, config ParserConfig) []string {
+    if isXLSFile(file.Name()) {
+        return validateXLSFileHeaders(file, config)
+    }
+
+    xl, closer, err := openXLSX(file)
+    if err != nil {
+        return []string{fmt.Sprintf("failed to open Excel file: %v", err)}
+    }
+    defer closer.Close()
+
+    sheets, err := selectSheets(xl.Sheets, config.Sheets)
+    if err != nil {
+        return []string{err.Error()}
+    }
+
+    var problems []string
+    for _, sheet := range sheets {
+        headers, err := readHeaders(xl, sheet)
+        if err == nil && len(config.Columns) > 0 {
+            _, err = mapColumns(headers, config.Columns)
+        }
         if err != nil {
-            return err
+            problems = append(problems, fmt.Sprintf("sheet %q: %v", sheet, err))
         }
-        
-        // Skip subdirectories
-        if d.IsDir() && path != dirPath {
-            return filepath.SkipDir
+    }
+
+    return problems
+}
+
+// selectSheets returns the sheets matching any of the names or patterns (e.g. "Data", "Nomenclature *"),
+// compared case-insensitively, in workbook order. Without patterns the first sheet is selected.
+func selectSheets(sheets []string, patterns []string) ([]string, error) {
+    if len(sheets) == 0 {
+        return nil, fmt.Errorf("no sheets found")
+    }
+    if len(patterns) == 0 {
+        return sheets[:1], nil
+    }
+
+    var selected []string
+    for _, sheet := range sheets {
+        for _, pattern := range patterns {
+            matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(sheet))
+            if err != nil {
+                return nil, fmt.Errorf("invalid sheet pattern %q: %v", pattern, err)
+            }
+            if matched {
+                selected = append(selected, sheet)
+                break
+            }
         }
-        
-        // Check if file is an Excel file (.xlsx, .xls)
-        if !d.IsDir() && isExcelFile(path) {
-            excelFiles = append(excelFiles, path)
+    }
+
+    if len(selected) == 0 {
+        return nil, fmt.Errorf("no sheet matching %q, found sheets %q", patterns, sheets)
+    }
+
+    return selected, nil
+}
+
+// openXLSX opens an .xlsx file. An entry of a zip archive is read into memory, as the workbook
+// is itself a zip archive which needs random access.
+func openXLSX(file inputFile) (*xlsxreader.XlsxFile, io.Closer, error) {
+    if file.Entry == "" {
+        xl, err := xlsxreader.OpenFile(file.Path)
+        if err != nil {
+            return nil, nil, err
         }
-        
-        return nil
-    })
+        return &xl.XlsxFile, xl, nil
+    }
 
+    data, err := file.ReadAll()
     if err != nil {
-        log.Printf("Error walking directory: %v", err)
-        close(rowsChan) // Close here for error case
-        return
+        return nil, nil, err
+    }
+
+    xl, err := xlsxreader.NewReader(data)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Nothing to close for a workbook in memory
+    return xl, io.NopCloser(nil), nil
+}
+
+// readHeaders reads the header row of a sheet
+func readHeaders(xl *xlsxreader.XlsxFile, sheet string) ([]string, error) {
+    for row := range xl.ReadRows(sheet) {
+        if row.Error != nil {
+            return nil, fmt.Errorf("failed to read header row: %v", row.Error)
+        }
+        return headerCells(row), nil
     }
 
+    return nil, fmt.Errorf("no header row found")
+}
+
+// processFiles reads the Excel files, up to config.Workers at a time, sends their rows to the channel in the
+// order of the files and closes it
+func processFiles(ctx context.Context, excelFiles []inputFile, config ParserConfig, rowsChan chan<- RowData) {
+    defer close(rowsChan)
+
     // No files found case
     if len(excelFiles) == 0 {
-        log.Printf("No Excel files found in directory: %s", dirPath)
-        close(rowsChan) // Close here for empty directory case
+        log.Printf("No Excel files found")
         return
     }
 
-    // Process each Excel file sequentially
+    fileNumbers := make(map[inputFile]int, len(excelFiles))
     for i, file := range excelFiles {
-        log.Printf("Processing Excel file %d/%d: %s", i+1, len(excelFiles), file)
-        
-        // Create a multiplexing channel for all files except the last
-        if i < len(excelFiles)-1 {
-            fileChan := make(chan RowData)
-            
-            // Start a goroutine to forward rows
-            go func(f string) {
-                // Forward all rows from this file to main channel
-                for row := range fileChan {
-                    rowsChan <- row
-                }
-            }(file)
-            
-            // Process file with its own channel
-            processFileWithoutClosing(file, fileChan)
-            close(fileChan) // Close the file's channel after processing
-        } else {
-            // For the last file, process and close the main channel when done
-            processFileWithoutClosing(file, rowsChan)
-            close(rowsChan) // Close main channel after last file
-        }
+        fileNumbers[file] = i + 1
     }
-}
 
-// processFile reads a single Excel file and sends rows to the channel, and closes the channel
-func processFile(filePath string, rowsChan chan<- RowData) {
-    processFileWithoutClosing(filePath, rowsChan)
-    close(rowsChan)
+    readFiles(ctx, excelFiles, config, rowsChan, func(file inputFile, fileChan chan<- RowData) {
+        if len(excelFiles) > 1 {
+            log.Printf("Processing Excel file %d/%d: %s", fileNumbers[file], len(excelFiles), file.Name())
+        }
+        processFileWithoutClosing(ctx, file, config, fileChan)
+    })
 }
 
-// processFileWithoutClosing reads a single Excel file and sends rows to the channel without closing it
-func processFileWithoutClosing(filePath string, rowsChan chan<- RowData) {
+// processFileWithoutClosing reads the selected sheets of a single Excel file and sends rows to the channel without closing it
+func processFileWithoutClosing(ctx context.Context, file inputFile, config ParserConfig, rowsChan chan<- RowData) {
     defer func() {
         if r := recover(); r != nil {
-            log.Printf("Recovered from panic while processing file %s: %v", filePath, r)
+            log.Printf("Recovered from panic while processing file %s: %v", file.Name(), r)
         }
     }()
 
-    // Extract filename for source tracking
-    filename := filepath.Base(filePath)
+    if isXLSFile(file.Name()) {
+        processXLSFile(ctx, file, config, rowsChan)
+        return
+    }
 
     // Open the Excel file
-    xl, err := xlsxreader.OpenFile(filePath)
+    xl, closer, err := openXLSX(file)
     if err != nil {
-        log.Printf("Failed to open Excel file %s: %v", filePath, err)
+        log.Printf("Failed to open Excel file %s: %v", file.Name(), err)
         return
     }
-    defer xl.Close()
+    defer closer.Close()
     
-    // Check if the sheet exists
-    if len(xl.Sheets) == 0 {
-        log.Printf("No sheets found in the Excel file %s", filePath)
+    sheets, err := selectSheets(xl.Sheets, config.Sheets)
+    if err != nil {
+        log.Printf("Failed to select sheets of the Excel file %s: %v", file.Name(), err)
         return
     }
 
-    firstRow := true
+    for _, sheet := range sheets {
+        if !processSheet(ctx, xl, file.Name(), sheet, config.Columns, rowsChan) {
+            return
+        }
+    }
+}
+
+// processSheet reads the rows of a sheet and sends them to the channel.
+// It returns false if the import was cancelled before every row was sent.
+func processSheet(ctx context.Context, xl *xlsxreader.XlsxFile, filename string, sheet string, columns []Column, rowsChan chan<- RowData) bool {
+    var headers []string
+    var columnIndexes map[string]int
     
     // Read rows from Excel and send to channel
-    for row := range xl.ReadRows(xl.Sheets[0]) {
-        // Skip the header row
-        if firstRow {
-            firstRow = false
+    for row := range xl.ReadRows(sheet) {
+        if row.Error != nil {
+            log.Printf("Failed to read %s, sheet %q, row %d: %v", filename, sheet, row.Index, row.Error)
             continue
         }
 
-        // Initialize cells with empty strings and map by column
-        cells := make([]string, 30) // Larger capacity for different parsers
-        for _, cell := range row.Cells {
-            colIndex := int(cell.Column[0] - 'A') // Convert column letter to index (A=0, B=1, etc.)
-            if colIndex >= 0 && colIndex < len(cells) {
-                cells[colIndex] = cell.Value
+        // The first row holds the headers
+        if headers == nil {
+            headers = headerCells(row)
+            if len(columns) > 0 {
+                var err error
+                columnIndexes, err = mapColumns(headers, columns)
+                if err != nil {
+                    log.Printf("Invalid headers in %s, sheet %q: %v", filename, sheet, err)
+                    return true
+                }
             }
+            continue
         }
 
-        rowsChan <- ExcelRow{
-            Cells:  cells,
-            Source: filename,
+        excelRow := ExcelRow{
+            Cells:   rowCells(row, len(headers)),
+            Headers: headers,
+            Width:   len(headers),
+            Source:  filename,
+            Sheet:   sheet,
+            Row:     row.Index,
+            columns: columnIndexes,
+        }
+        if !sendRow(ctx, rowsChan, excelRow) {
+            return false
+        }
+    }
+
+    return true
+}
+
+// rowCells maps the cells of a row by column, leaving empty strings for missing cells.
+// The result has at least width entries, more if the row has cells beyond the width.
+func rowCells(row xlsxreader.Row, width int) []string {
+    cells := make([]string, width)
+    for _, cell := range row.Cells {
+        colIndex := cell.ColumnIndex() // Handles multi-letter references (A=0, Z=25, AA=26, etc.)
+        if colIndex < 0 {
+            continue
+        }
+        for colIndex >= len(cells) {
+            cells = append(cells, "")
         }
+        cells[colIndex] = cell.Value
     }
+
+    return cells
+}
+
+// headerCells maps the header row by column, without the empty cells after the last header
+func headerCells(row xlsxreader.Row) []string {
+    return trimHeaders(rowCells(row, 0))
+}
+
+// trimHeaders removes the empty cells after the last header
+func trimHeaders(headers []string) []string {
+    for len(headers) > 0 && strings.TrimSpace(headers[len(headers)-1]) == "" {
+        headers = headers[:len(headers)-1]
+    }
+
+    return headers
 }
 
 // isExcelFile checks if the file has an Excel extension
 func isExcelFile(path string) bool {
     ext := strings.ToLower(filepath.Ext(path))
     return ext == ".xlsx" || ext == ".xls"
-}
\ No newline at end of file
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thedatashed/xlsxreader"
+)
+
+func TestRowCells(t *testing.T) {
+	row := func(references ...string) xlsxreader.Row {
+		var cells []xlsxreader.Cell
+		for _, reference := range references {
+			cells = append(cells, xlsxreader.Cell{Column: reference, Value: reference})
+		}
+		return xlsxreader.Row{Cells: cells}
+	}
+
+	tests := []struct {
+		name     string
+		row      xlsxreader.Row
+		width    int
+		expected []string
+	}{
+		{"Padded to width", row("A", "C"), 4, []string{"A", "", "C", ""}},
+		{"Beyond Z", row("A", "Z", "AA", "AD"), 30, append(append([]string{"A"}, make([]string, 24)...), "Z", "AA", "", "", "AD")},
+		{"Wider than width", row("B", "AB"), 2, append(append([]string{"", "B"}, make([]string, 25)...), "AB")},
+		{"Empty row", row(), 3, []string{"", "", ""}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cells := rowCells(tc.row, tc.width)
+			if !reflect.DeepEqual(cells, tc.expected) {
+				t.Errorf("rowCells() = %q, expected %q", cells, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHeaderCells(t *testing.T) {
+	headers := headerCells(xlsxreader.Row{Cells: []xlsxreader.Cell{
+		{Column: "A", Value: "Goods code"},
+		{Column: "AB", Value: "Duty"},
+		{Column: "AC", Value: " "},
+	}})
+
+	if len(headers) != 28 || headers[0] != "Goods code" || headers[27] != "Duty" {
+		t.Errorf("headerCells() = %q, expected 28 headers from Goods code to Duty", headers)
+	}
+}
+
+func TestSelectSheets(t *testing.T) {
+	sheets := []string{"Notes", "Nomenclature EN", "Nomenclature LT"}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+		err      bool
+	}{
+		{"First sheet by default", nil, []string{"Notes"}, false},
+		{"Name", []string{"Nomenclature LT"}, []string{"Nomenclature LT"}, false},
+		{"Case insensitive", []string{"nomenclature en"}, []string{"Nomenclature EN"}, false},
+		{"Pattern", []string{"Nomenclature *"}, []string{"Nomenclature EN", "Nomenclature LT"}, false},
+		{"Workbook order", []string{"Nomenclature LT", "Notes"}, []string{"Notes", "Nomenclature LT"}, false},
+		{"No match", []string{"Data"}, nil, true},
+		{"Invalid pattern", []string{"["}, nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := selectSheets(sheets, tc.patterns)
+			if tc.err {
+				if err == nil {
+					t.Errorf("selectSheets(%q) = %q, expected an error", tc.patterns, selected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectSheets(%q) returned error: %v", tc.patterns, err)
+			}
+			if !reflect.DeepEqual(selected, tc.expected) {
+				t.Errorf("selectSheets(%q) = %q, expected %q", tc.patterns, selected, tc.expected)
+			}
+		})
+	}
+}
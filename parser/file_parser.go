@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"muj/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Input file formats
+const (
+	formatExcel = "excel"
+	formatCSV   = "csv"
+	formatTSV   = "tsv"
+)
+
+// BaseFileParser reads rows from Excel, CSV or TSV files, picking the reader by config.Format
+// or by the file extension. Both readers produce ExcelRow, so parsers embedding it map rows
+// the same way regardless of the format.
+type BaseFileParser struct {
+	excel BaseExcelParser
+	csv   BaseCSVParser
+}
+
+// inputFile is a file to read, either on disk or an entry of a zip archive
+type inputFile struct {
+	Path  string // Path of the file on disk, or of the zip archive holding the entry
+	Entry string // Name of the entry in the zip archive, empty for a file on disk
+}
+
+// Name identifies the file in logs and ExcelRow.Source, e.g. "Nomenclature EN.xlsx",
+// or "nomenclatures.zip/Nomenclature EN.xlsx" for an entry of a zip archive
+func (f inputFile) Name() string {
+	if f.Entry == "" {
+		return filepath.Base(f.Path)
+	}
+
+	return filepath.Base(f.Path) + "/" + f.Entry
+}
+
+// Open opens the file for reading, streaming an entry of a zip archive without extracting it
+func (f inputFile) Open() (io.ReadCloser, error) {
+	if f.Entry == "" {
+		return os.Open(f.Path)
+	}
+
+	return openZipEntry(f.Path, f.Entry)
+}
+
+// ReadAll reads the whole file into memory, for readers which need random access to it
+func (f inputFile) ReadAll() ([]byte, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// ReadRows reads the file or directory with the reader of its format
+func (p *BaseFileParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	format, err := inputFormat(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatExcel:
+		return p.excel.ReadRows(ctx, config)
+	case formatCSV, formatTSV:
+		config.Format = format
+		return p.csv.ReadRows(ctx, config)
+	default:
+		return nil, fmt.Errorf("unknown format %q: expected %s, %s or %s", format, formatExcel, formatCSV, formatTSV)
+	}
+}
+
+// MatchFiles returns the matcher of the files read by the reader of the configured format, or of the format
+// of the input file extension
+func (p *BaseFileParser) MatchFiles(config ParserConfig) (func(path string) bool, error) {
+	format, err := inputFormat(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatExcel:
+		return isExcelFile, nil
+	case formatCSV, formatTSV:
+		return isCSVFile, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q: expected %s, %s or %s", format, formatExcel, formatCSV, formatTSV)
+	}
+}
+
+// fileFormat returns the format of a file by its extension, or an empty string if it is not supported
+func fileFormat(path string) string {
+	switch {
+	case isExcelFile(path):
+		return formatExcel
+	case strings.EqualFold(filepath.Ext(path), ".csv"):
+		return formatCSV
+	case strings.EqualFold(filepath.Ext(path), ".tsv"):
+		return formatTSV
+	default:
+		return ""
+	}
+}
+
+// inputFormat returns the configured format, or the format of the input file extension.
+// A directory has the format of the files in it, which must all be of the same format.
+func inputFormat(config ParserConfig) (string, error) {
+	if config.Format != "" {
+		return strings.ToLower(config.Format), nil
+	}
+
+	files, err := inputFiles(config, func(path string) bool { return fileFormat(path) != "" })
+	if err != nil {
+		return "", err
+	}
+
+	formats := make(map[string]bool)
+	for _, file := range files {
+		formats[fileFormat(file.Name())] = true
+	}
+
+	switch len(formats) {
+	case 0:
+		return "", fmt.Errorf("unknown format of %s: use -format to select it", config.FilePath)
+	case 1:
+		for format := range formats {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s contains files of several formats: use -format to select one", config.FilePath)
+}
+
+// inputFiles returns the file at config.FilePath, or the matching files directly inside it if it is a directory.
+// Zip archives, given directly or found in the directory, are expanded to their matching entries.
+func inputFiles(config ParserConfig, match func(path string) bool) ([]inputFile, error) {
+	if config.FilePath == "" {
+		return nil, fmt.Errorf("file path is required for file parsers")
+	}
+
+	// Get absolute path
+	absPath := utils.GetAbsolutePath(config.FilePath)
+
+	// Check if path is a file or directory
+	fileInfo, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %v", err)
+	}
+
+	if !fileInfo.IsDir() {
+		if isZipFile(absPath) {
+			entries, err := zipEntries(absPath, match)
+			return withoutSkippedFiles(entries, config), err
+		}
+		return withoutSkippedFiles([]inputFile{{Path: absPath}}, config), nil
+	}
+
+	var files []inputFile
+	err = filepath.WalkDir(absPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip subdirectories
+		if d.IsDir() && path != absPath {
+			return filepath.SkipDir
+		}
+
+		switch {
+		case d.IsDir():
+		case match(path):
+			files = append(files, inputFile{Path: path})
+		case isZipFile(path):
+			entries, err := zipEntries(path, match)
+			if err != nil {
+				return err
+			}
+			files = append(files, entries...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %v", err)
+	}
+
+	return withoutSkippedFiles(files, config), nil
+}
+
+// withoutSkippedFiles leaves out the files in config.SkipFiles
+func withoutSkippedFiles(files []inputFile, config ParserConfig) []inputFile {
+	if len(config.SkipFiles) == 0 {
+		return files
+	}
+
+	var kept []inputFile
+	for _, file := range files {
+		if !config.SkipFiles[file] {
+			kept = append(kept, file)
+		}
+	}
+
+	return kept
+}
+
+// fileReadAhead is the number of rows buffered for each file read ahead of the file being sent
+const fileReadAhead = 1000
+
+// readFiles reads the files with read, up to config.Workers files at a time, and sends their rows to the channel
+// in the order of the files. Rows of files read ahead are buffered until the files before them are sent.
+// Once the import is cancelled no more files are read, and read is expected to stop sending rows.
+func readFiles(ctx context.Context, files []inputFile, config ParserConfig, rowsChan chan<- RowData, read func(file inputFile, rowsChan chan<- RowData)) {
+	if config.Workers <= 1 || len(files) <= 1 {
+		for _, file := range files {
+			if ctx.Err() != nil {
+				return
+			}
+			read(file, rowsChan)
+		}
+		return
+	}
+
+	fileChans := make([]chan RowData, len(files))
+	for i := range fileChans {
+		fileChans[i] = make(chan RowData, fileReadAhead)
+	}
+
+	// Readers start in the order of the files, so the files being read are always the first ones not yet sent
+	slots := make(chan struct{}, config.Workers)
+	go func() {
+		for i, file := range files {
+			slots <- struct{}{}
+			if ctx.Err() != nil {
+				// Files not read are closed empty
+				for _, fileChan := range fileChans[i:] {
+					close(fileChan)
+				}
+				return
+			}
+			go func(file inputFile, fileChan chan<- RowData) {
+				defer func() { <-slots }()
+				defer close(fileChan)
+				read(file, fileChan)
+			}(file, fileChans[i])
+		}
+	}()
+
+	// Once the import is cancelled, rows are dropped until the readers stop
+	for _, fileChan := range fileChans {
+		for row := range fileChan {
+			sendRow(ctx, rowsChan, row)
+		}
+	}
+}
+
+// sendRow sends a row to the channel, returning false without sending it once the import is cancelled
+func sendRow(ctx context.Context, rowsChan chan<- RowData, row RowData) bool {
+	select {
+	case rowsChan <- row:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// FootnoteEntry represents a single row from the TARIC footnotes export.
+// A row describes a footnote in one language and, optionally, associates it with a goods code
+// or with the measures of a goods code.
+type FootnoteEntry struct {
+	FootnoteType     string     // Footnote type (e.g. "TN", "CD")
+	Code             string     // 3-character footnote code within its type
+	StartDate        time.Time  // Validity start date of the footnote, or of the association if the row has a goods code
+	EndDate          *time.Time // Validity end date of the footnote or association (can be empty)
+	Language         string     // Language code of the description
+	Description      string     // Description of the footnote
+	GoodsCode        string     // Associated goods code, empty if the row has no association
+	MeasureType      string     // Measure type of the associated measures, empty for a goods code association
+	GeographicalArea string     // Origin of the associated measures, empty for measures of any origin
+}
+
+// FootnotesParser implements the Parser interface for footnote files.
+// Rows without a goods code define footnotes, and association rows are saved in order after them.
+type FootnotesParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the footnotes export
+func (p *FootnotesParser) Columns() []Column {
+	return []Column{
+		{Name: "Footnote"},
+		startDateColumn,
+		endDateColumn,
+		languageColumn,
+		descriptionColumn,
+		goodsCodeColumn.optional(),
+		{Name: "Meas. type code", Aliases: []string{"Measure type code"}, Optional: true},
+		{Name: "Origin code", Optional: true},
+	}
+}
+
+// MapRow converts an ExcelRow of the footnotes export to a FootnoteEntry.
+func (p *FootnotesParser) MapRow(rowData RowData) (FootnoteEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return FootnoteEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	footnote := strings.ReplaceAll(row.Value("Footnote"), " ", "")
+	if len(footnote) != 5 {
+		return FootnoteEntry{}, fmt.Errorf("invalid footnote %q: expected a 2-character type and 3-character code", row.Value("Footnote"))
+	}
+
+	entry := FootnoteEntry{
+		FootnoteType:     footnote[:2],
+		Code:             footnote[2:],
+		Language:         strings.TrimSpace(row.Value("Language")),
+		Description:      strings.TrimSpace(row.Value("Description")),
+		MeasureType:      strings.TrimSpace(row.Value("Meas. type code")),
+		GeographicalArea: strings.TrimSpace(row.Value("Origin code")),
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return FootnoteEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return FootnoteEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	if strings.TrimSpace(row.Value("Goods code")) != "" {
+		entry.GoodsCode, err = normalizeGoodsCode(row.Value("Goods code"))
+		if err != nil {
+			return FootnoteEntry{}, err
+		}
+	} else if entry.MeasureType != "" {
+		return FootnoteEntry{}, fmt.Errorf("goods code is required for a measure association")
+	}
+
+	return entry, nil
+}
+
+// SavesSequentially makes association rows be saved after the rows defining their footnotes
+func (p *FootnotesParser) SavesSequentially() bool {
+	return true
+}
+
+// ProcessEntry performs no additional processing for footnotes
+func (p *FootnotesParser) ProcessEntry(entry *FootnoteEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of footnote entries to the database
+func (p *FootnotesParser) SaveEntries(ctx context.Context, db *sql.DB, entries []FootnoteEntry) (int, error) {
+	return insertFootnoteEntries(ctx, db, entries)
+}
+
+func insertFootnoteEntries(ctx context.Context, db *sql.DB, entries []FootnoteEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Footnote definitions keep every validity period of a footnote
+	footnoteStmt, err := tx.Prepare(`
+		INSERT INTO footnotes (footnote_type, code, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (footnote_type, code, start_date)
+		DO UPDATE SET end_date = $4, updated_at = NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare footnote statement: %v", err)
+	}
+	defer footnoteStmt.Close()
+
+	// Association rows are dated by the association, which is made with the latest period of the footnote
+	// overlapping it
+	findFootnoteStmt, err := tx.Prepare(`
+		SELECT id FROM footnotes
+		WHERE footnote_type = $1 AND code = $2
+			AND start_date <= COALESCE($4::date, 'infinity') AND (end_date IS NULL OR end_date >= $3)
+		ORDER BY start_date DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare footnote lookup statement: %v", err)
+	}
+	defer findFootnoteStmt.Close()
+
+	descStmt, err := tx.Prepare(`
+		INSERT INTO footnote_descriptions (footnote_id, language, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (footnote_id, language)
+		DO UPDATE SET description = $3, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare description statement: %v", err)
+	}
+	defer descStmt.Close()
+
+	// Goods code associations apply to every validity period of the goods code. The statements return the number
+	// of rows matched, as associations imported before are not inserted again.
+	nomenclatureStmt, err := tx.Prepare(`
+		WITH matched AS (
+			SELECT id FROM nomenclatures WHERE goods_code = $2
+		), inserted AS (
+			INSERT INTO footnote_nomenclatures (footnote_id, nomenclature_id)
+			SELECT $1, id FROM matched
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM matched
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare goods code association statement: %v", err)
+	}
+	defer nomenclatureStmt.Close()
+
+	// Measure associations apply to the validity periods of the measures overlapping the association
+	measureStmt, err := tx.Prepare(`
+		WITH matched AS (
+			SELECT id FROM measures
+			WHERE goods_code = $2 AND measure_type_code = $3 AND ($4::text = '' OR geographical_area_code = $4)
+				AND start_date <= COALESCE($6::date, 'infinity') AND (end_date IS NULL OR end_date >= $5)
+		), inserted AS (
+			INSERT INTO footnote_measures (footnote_id, measure_id)
+			SELECT $1, id FROM matched
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM matched
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure association statement: %v", err)
+	}
+	defer measureStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		footnote := entry.FootnoteType + entry.Code
+
+		// A row without a goods code defines the footnote, the others associate it
+		var footnoteID int
+		if entry.GoodsCode == "" {
+			err = footnoteStmt.QueryRow(entry.FootnoteType, entry.Code, entry.StartDate, entry.EndDate).Scan(&footnoteID)
+			if err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert footnote %s: %v", footnote, err)
+			}
+		} else {
+			err = findFootnoteStmt.QueryRow(entry.FootnoteType, entry.Code, entry.StartDate, entry.EndDate).Scan(&footnoteID)
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Printf("no footnote %s valid from %s found for association with %s", footnote, entry.StartDate.Format("2006-01-02"), entry.GoodsCode)
+				successCount++
+				continue
+			}
+			if err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to find footnote %s: %v", footnote, err)
+			}
+		}
+
+		if entry.Language != "" && entry.Description != "" {
+			if _, err := descStmt.Exec(footnoteID, entry.Language, entry.Description); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert description for footnote %s: %v", footnote, err)
+			}
+		}
+
+		if entry.GoodsCode != "" {
+			var matched int
+			if entry.MeasureType == "" {
+				err = nomenclatureStmt.QueryRow(footnoteID, entry.GoodsCode).Scan(&matched)
+			} else {
+				err = measureStmt.QueryRow(footnoteID, entry.GoodsCode, entry.MeasureType, entry.GeographicalArea, entry.StartDate, entry.EndDate).Scan(&matched)
+			}
+			if err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to associate footnote %s with %s: %v", footnote, entry.GoodsCode, err)
+			}
+
+			if matched == 0 && entry.MeasureType == "" {
+				log.Printf("no goods code %s found for association of footnote %s", entry.GoodsCode, footnote)
+			} else if matched == 0 {
+				log.Printf("no measure of type %s on goods code %s valid from %s found for association of footnote %s",
+					entry.MeasureType, entry.GoodsCode, entry.StartDate.Format("2006-01-02"), footnote)
+			}
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
@@ -0,0 +1,61 @@
+-- Table to store TARIC footnotes (e.g. TN701, CD624)
+CREATE TABLE footnotes (
+    id SERIAL PRIMARY KEY,
+    footnote_type VARCHAR(2) NOT NULL,
+    code VARCHAR(3) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(footnote_type, code, start_date)
+);
+
+-- Table to store localized footnote descriptions
+CREATE TABLE footnote_descriptions (
+    id SERIAL PRIMARY KEY,
+    footnote_id INTEGER NOT NULL REFERENCES footnotes(id) ON DELETE CASCADE,
+    language CHAR(2) NOT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(footnote_id, language)
+);
+
+-- Footnotes explaining goods codes
+CREATE TABLE footnote_nomenclatures (
+    footnote_id INTEGER NOT NULL REFERENCES footnotes(id) ON DELETE CASCADE,
+    nomenclature_id INTEGER NOT NULL REFERENCES nomenclatures(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (footnote_id, nomenclature_id)
+);
+
+-- Footnotes explaining conditions of measures
+CREATE TABLE footnote_measures (
+    footnote_id INTEGER NOT NULL REFERENCES footnotes(id) ON DELETE CASCADE,
+    measure_id INTEGER NOT NULL REFERENCES measures(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (footnote_id, measure_id)
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_footnote_descriptions_language ON footnote_descriptions(language);
+CREATE INDEX idx_footnote_nomenclatures_nomenclature_id ON footnote_nomenclatures(nomenclature_id);
+CREATE INDEX idx_footnote_measures_measure_id ON footnote_measures(measure_id);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_footnotes_modtime
+BEFORE UPDATE ON footnotes
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_footnote_descriptions_modtime
+BEFORE UPDATE ON footnote_descriptions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
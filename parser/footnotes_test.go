@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInsertFootnoteEntries(t *testing.T) {
+	db := testDB(t)
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM footnotes WHERE footnote_type = 'ZZ'`); err != nil {
+			t.Error(err)
+		}
+	})
+
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	// A measure starting in 2021 on a goods code valid since 2000
+	hierarchyPath, err := getHierarchyPath("7703000000 80", hierarchyLevel("7703000000 80"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		WITH nomenclature AS (
+			INSERT INTO nomenclatures (goods_code, start_date, hierarchy_path, indent)
+			VALUES ('7703000000 80', '2000-01-01', $1::ltree, 0)
+			RETURNING id
+		), measure_type AS (
+			INSERT INTO measure_types (code, description) VALUES ('103', '') ON CONFLICT (code) DO NOTHING
+		)
+		INSERT INTO measures (nomenclature_id, goods_code, measure_type_code, geographical_area_code, start_date)
+		SELECT id, '7703000000 80', '103', '1011', '2021-01-01' FROM nomenclature
+	`, hierarchyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstEnd := date("2014-12-31")
+	entries := []FootnoteEntry{
+		// Two validity periods of the footnote
+		{FootnoteType: "ZZ", Code: "901", StartDate: date("2007-01-01"), EndDate: &firstEnd, Language: "EN", Description: "First"},
+		{FootnoteType: "ZZ", Code: "901", StartDate: date("2015-01-01"), Language: "EN", Description: "Second"},
+		// An association older than its measure
+		{FootnoteType: "ZZ", Code: "901", StartDate: date("2007-01-01"), GoodsCode: "7703000000 80", MeasureType: "103"},
+	}
+	if _, err := insertFootnoteEntries(context.Background(), db, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	var periods int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM footnotes WHERE footnote_type = 'ZZ' AND code = '901'`).Scan(&periods); err != nil {
+		t.Fatal(err)
+	}
+	if periods != 2 {
+		t.Errorf("footnote has %d validity periods, expected 2", periods)
+	}
+
+	var startDate time.Time
+	err = db.QueryRow(`
+		SELECT f.start_date FROM footnote_measures fm
+		JOIN footnotes f ON fm.footnote_id = f.id
+		JOIN measures m ON fm.measure_id = m.id
+		WHERE f.footnote_type = 'ZZ' AND m.goods_code = '7703000000 80'
+	`).Scan(&startDate)
+	if err != nil {
+		t.Fatalf("footnote is not associated with the measure: %v", err)
+	}
+	if !startDate.Equal(date("2015-01-01")) {
+		t.Errorf("measure is associated with the footnote period from %s, expected 2015-01-01", startDate.Format("2006-01-02"))
+	}
+}
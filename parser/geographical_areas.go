@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GeographicalAreaEntry represents a single row from the geographical areas export.
+// A row describes an area in one language and, optionally, its membership in a country group.
+type GeographicalAreaEntry struct {
+	AreaID              string     // Area identifier: a country (e.g. "CN") or a group (e.g. "1011" ERGA OMNES)
+	AreaCode            string     // Area type: "0" = country, "1" = country group, "2" = region
+	StartDate           time.Time  // Validity start date of the area
+	EndDate             *time.Time // Validity end date of the area (can be empty)
+	Language            string     // Language code of the description
+	Description         string     // Description of the area
+	GroupID             string     // Country group the area is a member of, empty if none
+	MembershipStartDate time.Time  // Start date of the group membership, defaults to the area start date
+	MembershipEndDate   *time.Time // End date of the group membership (can be empty)
+}
+
+// GeographicalAreasParser implements the Parser interface for geographical area files
+type GeographicalAreasParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the geographical areas export
+func (p *GeographicalAreasParser) Columns() []Column {
+	return []Column{
+		{Name: "Area ID"},
+		{Name: "Area code"},
+		startDateColumn,
+		endDateColumn,
+		languageColumn,
+		descriptionColumn,
+		{Name: "Member of", Optional: true},
+		{Name: "Membership start date", Optional: true},
+		{Name: "Membership end date", Optional: true},
+	}
+}
+
+// MapRow converts an ExcelRow of the geographical areas export to a GeographicalAreaEntry.
+func (p *GeographicalAreasParser) MapRow(rowData RowData) (GeographicalAreaEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return GeographicalAreaEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	entry := GeographicalAreaEntry{
+		AreaID:      strings.TrimSpace(row.Value("Area ID")),
+		AreaCode:    strings.TrimSpace(row.Value("Area code")),
+		Language:    strings.TrimSpace(row.Value("Language")),
+		Description: strings.TrimSpace(row.Value("Description")),
+		GroupID:     strings.TrimSpace(row.Value("Member of")),
+	}
+
+	if entry.AreaID == "" {
+		return GeographicalAreaEntry{}, fmt.Errorf("area ID is required")
+	}
+	if entry.AreaCode != "0" && entry.AreaCode != "1" && entry.AreaCode != "2" {
+		return GeographicalAreaEntry{}, fmt.Errorf("invalid area code %q: expected 0, 1 or 2", entry.AreaCode)
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return GeographicalAreaEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return GeographicalAreaEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	entry.MembershipStartDate = entry.StartDate
+	membershipStartDate, err := parseOptionalDate(row.Value("Membership start date"), "02-01-2006")
+	if err != nil {
+		return GeographicalAreaEntry{}, fmt.Errorf("invalid membership start date format: %v", err)
+	}
+	if membershipStartDate != nil {
+		entry.MembershipStartDate = *membershipStartDate
+	}
+
+	entry.MembershipEndDate, err = parseOptionalDate(row.Value("Membership end date"), "02-01-2006")
+	if err != nil {
+		return GeographicalAreaEntry{}, fmt.Errorf("invalid membership end date format: %v", err)
+	}
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for geographical areas
+func (p *GeographicalAreasParser) ProcessEntry(entry *GeographicalAreaEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of geographical area entries to the database
+func (p *GeographicalAreasParser) SaveEntries(ctx context.Context, db *sql.DB, entries []GeographicalAreaEntry) (int, error) {
+	return insertGeographicalAreaEntries(ctx, db, entries)
+}
+
+func insertGeographicalAreaEntries(ctx context.Context, db *sql.DB, entries []GeographicalAreaEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	areaStmt, err := tx.Prepare(`
+		INSERT INTO geographical_areas (area_id, area_code, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (area_id)
+		DO UPDATE SET area_code = $2, start_date = $3, end_date = $4, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare area statement: %v", err)
+	}
+	defer areaStmt.Close()
+
+	descStmt, err := tx.Prepare(`
+		INSERT INTO geographical_area_descriptions (area_id, language, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (area_id, language)
+		DO UPDATE SET description = $3, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare description statement: %v", err)
+	}
+	defer descStmt.Close()
+
+	// A group may be listed after its members, so a placeholder is created until its own row is imported
+	groupStmt, err := tx.Prepare(`
+		INSERT INTO geographical_areas (area_id, area_code, start_date)
+		VALUES ($1, '1', $2)
+		ON CONFLICT (area_id) DO NOTHING
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare group statement: %v", err)
+	}
+	defer groupStmt.Close()
+
+	membershipStmt, err := tx.Prepare(`
+		INSERT INTO geographical_area_memberships (group_id, member_id, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (group_id, member_id, start_date)
+		DO UPDATE SET end_date = $4, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare membership statement: %v", err)
+	}
+	defer membershipStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		if _, err := areaStmt.Exec(entry.AreaID, entry.AreaCode, entry.StartDate, entry.EndDate); err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert area %s: %v", entry.AreaID, err)
+		}
+
+		if entry.Language != "" && entry.Description != "" {
+			if _, err := descStmt.Exec(entry.AreaID, entry.Language, entry.Description); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert description for area %s: %v", entry.AreaID, err)
+			}
+		}
+
+		if entry.GroupID != "" {
+			if _, err := groupStmt.Exec(entry.GroupID, entry.MembershipStartDate); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert group %s: %v", entry.GroupID, err)
+			}
+
+			if _, err := membershipStmt.Exec(entry.GroupID, entry.AreaID, entry.MembershipStartDate, entry.MembershipEndDate); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert membership of area %s in group %s: %v", entry.AreaID, entry.GroupID, err)
+			}
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
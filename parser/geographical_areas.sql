@@ -0,0 +1,58 @@
+-- Table to store geographical areas: countries (e.g. CN), country groups (e.g. 1011 ERGA OMNES, 2005 GSP) and regions
+CREATE TABLE geographical_areas (
+    area_id VARCHAR(4) PRIMARY KEY,
+    area_code CHAR(1) NOT NULL, -- 0 = country, 1 = country group, 2 = region
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table to store localized geographical area descriptions
+CREATE TABLE geographical_area_descriptions (
+    id SERIAL PRIMARY KEY,
+    area_id VARCHAR(4) NOT NULL REFERENCES geographical_areas(area_id) ON DELETE CASCADE,
+    language CHAR(2) NOT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(area_id, language)
+);
+
+-- Table to store dated memberships of areas in country groups
+CREATE TABLE geographical_area_memberships (
+    id SERIAL PRIMARY KEY,
+    group_id VARCHAR(4) NOT NULL REFERENCES geographical_areas(area_id) ON DELETE CASCADE,
+    member_id VARCHAR(4) NOT NULL REFERENCES geographical_areas(area_id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(group_id, member_id, start_date)
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_geographical_area_descriptions_language ON geographical_area_descriptions(language);
+CREATE INDEX idx_geographical_area_memberships_member_id ON geographical_area_memberships(member_id);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_geographical_areas_modtime
+BEFORE UPDATE ON geographical_areas
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_geographical_area_descriptions_modtime
+BEFORE UPDATE ON geographical_area_descriptions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_geographical_area_memberships_modtime
+BEFORE UPDATE ON geographical_area_memberships
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -2,4 +2,10 @@ module muj/parser
 
 go 1.23.4
 
-require github.com/thedatashed/xlsxreader v1.2.8
+require (
+	github.com/shakinm/xlsReader v0.9.12
+	github.com/thedatashed/xlsxreader v1.2.8
+	golang.org/x/text v0.23.0
+)
+
+require github.com/metakeule/fmtdate v1.1.2 // indirect
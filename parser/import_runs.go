@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Statuses of an import run
+const (
+	importRunning     = "running"
+	importSucceeded   = "succeeded"
+	importPartial     = "partial" // Every row was read but some were rejected, so the files are not skipped later
+	importFailed      = "failed"
+	importSkipped     = "skipped"     // Every file was already imported
+	importInterrupted = "interrupted" // Stopped by SIGINT or SIGTERM, the batches saved before are kept
+)
+
+// importFile is an input file of an import run with its checksum
+type importFile struct {
+	File       inputFile
+	SHA256     string
+	Size       int64
+	ImportedBy int // Earlier successful run without rejected rows which imported a file with the same checksum, 0 if none
+}
+
+// importRun is a run of the parser recorded in import_runs
+type importRun struct {
+	ID    int
+	Files []importFile
+}
+
+// isInputFile checks if the file has the extension of a format read by any parser, used for parsers which are
+// not a FileMatcher
+func isInputFile(path string) bool {
+	return fileFormat(path) != "" || isXMLFile(path)
+}
+
+// checksumInputFiles returns the input files at config.FilePath read by the parser, those accepted by match, with
+// their SHA-256 checksums
+func checksumInputFiles(config ParserConfig, match func(path string) bool) ([]importFile, error) {
+	files, err := inputFiles(config, match)
+	if err != nil {
+		return nil, err
+	}
+
+	importFiles := make([]importFile, len(files))
+	for i, file := range files {
+		checksum, size, err := fileChecksum(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %s: %v", file.Name(), err)
+		}
+		importFiles[i] = importFile{File: file, SHA256: checksum, Size: size}
+	}
+
+	return importFiles, nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum and the size of a file
+func fileChecksum(file inputFile) (string, int64, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// findImportedFiles sets ImportedBy of the files imported by an earlier successful run of the same parser type.
+// Runs recorded as succeeded before partial runs were told apart are left out if they rejected rows.
+func findImportedFiles(db *sql.DB, parserType string, files []importFile) error {
+	stmt, err := db.Prepare(`
+		SELECT r.id FROM import_run_files f
+		JOIN import_runs r ON f.import_run_id = r.id
+		WHERE r.parser_type = $1 AND r.status = 'succeeded' AND r.rows_errored = 0 AND f.sha256 = $2 AND NOT f.skipped
+		ORDER BY r.id DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare imported file statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i := range files {
+		err := stmt.QueryRow(parserType, files[i].SHA256).Scan(&files[i].ImportedBy)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to find earlier imports of %s: %v", files[i].File.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// startImportRun records a running import of the files, those already imported being skipped, and the run
+// it resumes, if any
+func startImportRun(db *sql.DB, config ParserConfig, files []importFile) (*importRun, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	run := &importRun{Files: files}
+	err = tx.QueryRow(`
+		INSERT INTO import_runs (parser_type, path, status, resumed_from)
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+		RETURNING id
+	`, config.ParserType, config.FilePath, importRunning, config.Resume.RunID).Scan(&run.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record import run: %v", err)
+	}
+
+	fileStmt, err := tx.Prepare(`
+		INSERT INTO import_run_files (import_run_id, file_name, sha256, size, skipped)
+		VALUES ($1, $2, $3, $4, $5)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to prepare import file statement: %v", err)
+	}
+	defer fileStmt.Close()
+
+	for _, file := range files {
+		if _, err := fileStmt.Exec(run.ID, file.File.Name(), file.SHA256, file.Size, config.SkipFiles[file.File]); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to record import file %s: %v", file.File.Name(), err)
+		}
+	}
+
+	// A resumed run starts at the checkpoint of the run it continues, in case it fails before saving a batch
+	if config.Resume.Rows > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO import_checkpoints (import_run_id, file_name, sheet, row_number, rows_committed)
+			VALUES ($1, $2, $3, $4, $5)
+		`, run.ID, config.Resume.Source, config.Resume.Sheet, config.Resume.Row, config.Resume.Rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to record checkpoint of import run: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// finish records the outcome of the import run
+func (r *importRun) finish(db *sql.DB, status string, processed, inserted, errored int, runErr error) error {
+	errorMessage := ""
+	if runErr != nil {
+		errorMessage = runErr.Error()
+	}
+
+	_, err := db.Exec(`
+		UPDATE import_runs
+		SET status = $2, rows_processed = $3, rows_inserted = $4, rows_errored = $5, error = $6, finished_at = NOW()
+		WHERE id = $1
+	`, r.ID, status, processed, inserted, errored, errorMessage)
+	if err != nil {
+		return fmt.Errorf("failed to record outcome of import run %d: %v", r.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+-- Table to store every run of the parser, as an audit trail of the files the database reflects
+CREATE TABLE import_runs (
+    id SERIAL PRIMARY KEY,
+    parser_type TEXT NOT NULL,
+    path TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('running', 'succeeded', 'partial', 'failed', 'skipped', 'interrupted')),
+    rows_processed INTEGER NOT NULL DEFAULT 0,
+    rows_inserted INTEGER NOT NULL DEFAULT 0,
+    rows_errored INTEGER NOT NULL DEFAULT 0,
+    resumed_from INTEGER REFERENCES import_runs(id), -- Failed or interrupted run continued by -resume
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Files read by an import run, or skipped as a file with the same checksum was already imported
+CREATE TABLE import_run_files (
+    id SERIAL PRIMARY KEY,
+    import_run_id INTEGER NOT NULL REFERENCES import_runs(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,     -- e.g. "Nomenclature EN.xlsx", or "nomenclatures.zip/Nomenclature EN.xlsx"
+    sha256 CHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    skipped BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Last row of an import run whose batch, and every batch before it, is committed, updated after each batch.
+-- An import run with -resume skips the rows of the import up to and including it.
+CREATE TABLE import_checkpoints (
+    import_run_id INTEGER PRIMARY KEY REFERENCES import_runs(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL DEFAULT '',
+    sheet TEXT NOT NULL DEFAULT '',
+    row_number INTEGER NOT NULL,     -- Row number as shown in Excel, line number in a CSV file, or position in the import
+    rows_committed INTEGER NOT NULL, -- Rows of the import read up to and including the row
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_import_runs_parser_type ON import_runs(parser_type, status);
+CREATE INDEX idx_import_runs_path ON import_runs(parser_type, path);
+CREATE INDEX idx_import_run_files_sha256 ON import_run_files(sha256);
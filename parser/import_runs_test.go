@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChecksumInputFilesOfParser(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Nomenclature EN.xlsx", "DIFF_230045.xml", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Input paths are relative to the parser directory, the working directory of the test
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := filepath.Rel(workDir, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		parserType string
+		expected   []string
+	}{
+		{"nomenclature", []string{"Nomenclature EN.xlsx"}},
+		{"taric_deltas", []string{"DIFF_230045.xml"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.parserType, func(t *testing.T) {
+			parser, err := createParser(tc.parserType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config := ParserConfig{ParserType: tc.parserType, FilePath: path}
+			match, err := parser.(FileMatcher).MatchFiles(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := checksumInputFiles(config, match)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, file := range files {
+				names = append(names, file.File.Name())
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("checksumInputFiles() = %q, expected %q", names, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContexts returns a context cancelled by the first SIGINT or SIGTERM, which stops reading rows while
+// the batches being saved are finished, and a context cancelled by the second one, which rolls them back.
+// stop releases the signals.
+func interruptContexts() (ctx context.Context, saveCtx context.Context, stop func()) {
+	ctx, stopNotify := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	saveCtx, cancelSaves := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+		log.Printf("Interrupted, finishing the batches being saved; interrupt again to roll them back")
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		select {
+		case <-signals:
+			log.Printf("Interrupted again, rolling back the batches being saved")
+			cancelSaves()
+		case <-done:
+		}
+	}()
+
+	return ctx, saveCtx, func() {
+		close(done)
+		stopNotify()
+		cancelSaves()
+	}
+}
@@ -1,29 +1,77 @@
 package main
 
 import (
+    "context"
     "database/sql"
     "flag"
     "fmt"
     "log"
     "muj/database"
+    "os"
+    "runtime"
 )
 
 // ParserConfig holds configuration for the parser
 type ParserConfig struct {
-	ParserType string // Type of parser to use
-	FilePath   string // Optional path to the file to parse (can be empty if parser uses other data sources)
-	ChunkSize  int    // Size of chunks to process
+	ParserType string   // Type of parser to use
+	FilePath   string   // Optional path to the file to parse (can be empty if parser uses other data sources)
+	ChunkSize  int      // Size of chunks to process
+	Columns    []Column // Columns the parser reads by header name, empty if it reads cells by position
+	Sheets     []string // Names or patterns of the sheets to read, the first sheet if empty
+	Format     string   // Input file format: "excel", "csv" or "tsv", picked by file extension if empty
+	CSV        CSVOptions
+	SkipFiles  map[inputFile]bool // Input files already imported, left out when reading the path
+	Workers    int                // Number of files read and rows mapped concurrently
+	Savers     int                // Number of batches saved concurrently, each in its own transaction
+	Bulk       bool               // Save batches with BulkSaveEntries, if the parser is a BulkSaver
+	Resume     importCheckpoint   // Checkpoint of the import run continued with -resume, zero if not resuming
 }
 
 // RowData represents a single row of data from any source
 type RowData interface{}
 
-// Parser interface that all parsers must implement
-type Parser interface {
-    ReadRows(config ParserConfig) (<-chan RowData, error)  // Returns a channel of rows from the data source
-    MapRow(RowData) (interface{}, error)  // Maps row data to a specific entry type
-    ProcessEntry(*interface{}) error        // Performs any processing on an entry before it's added to the batch
-    SaveEntries(db *sql.DB, entries []interface{}) (int, error)  // Saves a batch of entries to the database
+// SheetParser is implemented by parsers reading workbooks with several sheets, declaring the names
+// or patterns of the sheets holding their data. Other parsers read the first sheet.
+type SheetParser interface {
+	Sheets() []string
+}
+
+// Validator is implemented by parsers checking invariants across the entries of an import, run by -dry-run
+// once every row is mapped and processed
+type Validator[T any] interface {
+	Validate(entries []T) []ValidationProblem
+}
+
+// ValidationProblem is an invariant which does not hold for an entry
+type ValidationProblem struct {
+	Index   int // Index of the entry the problem is found at
+	Message string
+}
+
+// FileMatcher is implemented by parsers reading files, matching the files their ReadRows reads at config.FilePath,
+// so an import run records and checks only those. Other parsers record every file of a supported format.
+type FileMatcher interface {
+	MatchFiles(config ParserConfig) (func(path string) bool, error)
+}
+
+// SequentialSaver is implemented by parsers whose batches build on the batches before them, which are
+// saved one at a time whatever the number of savers
+type SequentialSaver interface {
+	SavesSequentially() bool
+}
+
+// BulkSaver is implemented by parsers which can save a batch with COPY into a staging table merged by
+// set-based statements, used instead of SaveEntries with -bulk
+type BulkSaver[T any] interface {
+	BulkSaveEntries(ctx context.Context, db *sql.DB, entries []T) (int, error)
+}
+
+// Parser interface that all parsers must implement, mapping rows to entries of type T
+type Parser[T any] interface {
+    ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error)  // Returns a channel of rows from the data source, closed early once ctx is cancelled
+    MapRow(RowData) (T, error)  // Maps row data to an entry
+    ProcessEntry(*T) error      // Performs any processing on an entry before it's added to the batch
+    SaveEntries(ctx context.Context, db *sql.DB, entries []T) (int, error)  // Saves a batch of entries to the database, rolled back if ctx is cancelled
 }
 
 func main() {
@@ -31,6 +79,19 @@ func main() {
     parserType := flag.String("type", "nomenclature", "Type of parser to use")
     filePath := flag.String("file", "", "Path to the file to parse. E.g ./files/nomenclatures/Nomenclature EN.xlsx")
     chunkSize := flag.Int("chunk", 1000, "Size of chunks to process")
+    format := flag.String("format", "", "Input file format: excel, csv or tsv. Defaults to the format of the file extension")
+    delimiter := flag.String("delimiter", "", "Field delimiter of CSV files. Defaults to a comma, or a tab for TSV files")
+    quote := flag.String("quote", "", "Quote character of CSV fields, e.g. ' for single quoted files. Defaults to \"")
+    lazyQuotes := flag.Bool("lazy-quotes", false, "Allow bare quotes in CSV fields")
+    encoding := flag.String("encoding", "", "Character encoding of CSV files, e.g. windows-1257. Defaults to UTF-8")
+    sheet := flag.String("sheet", "", "Name or pattern of the sheets to read, e.g. \"Data\" or \"Nomenclature *\". Defaults to the sheets declared by the parser or the first sheet")
+    force := flag.Bool("force", false, "Import files again even if a file with the same checksum was already imported successfully")
+    resume := flag.Bool("resume", false, "Continue the last failed or interrupted import of the same type and path after its last committed row")
+    dryRunFlag := flag.Bool("dry-run", false, "Read, map and validate every row and print a report, without writing to the database")
+    workers := flag.Int("workers", runtime.NumCPU(), "Number of files read and rows mapped concurrently")
+    savers := flag.Int("savers", 1, "Number of batches saved concurrently, each in its own transaction")
+    bulk := flag.Bool("bulk", false, "Save batches with COPY into a staging table, if the parser supports it")
+    rejectsPath := flag.String("rejects", "", "File to write rejected rows to, as JSON lines or CSV if it ends with .csv. E.g ./rejects.jsonl")
     flag.Parse()
 
     // Create parser configuration
@@ -38,6 +99,98 @@ func main() {
         ParserType: *parserType,
         FilePath:   *filePath,
         ChunkSize:  *chunkSize,
+        Format:     *format,
+        Workers:    *workers,
+        Savers:     *savers,
+        Bulk:       *bulk,
+        CSV: CSVOptions{
+            LazyQuotes: *lazyQuotes,
+            Encoding:   *encoding,
+        },
+    }
+
+    if *delimiter != "" {
+        delimiterRune, err := parseDelimiter(*delimiter)
+        if err != nil {
+            log.Fatal(err)
+        }
+        config.CSV.Delimiter = delimiterRune
+    }
+    if *quote != "" {
+        quoteRune, err := parseQuote(*quote)
+        if err != nil {
+            log.Fatal(err)
+        }
+        if quoteRune == config.CSV.Delimiter {
+            log.Fatalf("invalid quote %q: the same as the delimiter", *quote)
+        }
+        config.CSV.Quote = quoteRune
+    }
+
+    // Get the appropriate parser based on type
+    parser, err := createParser(config.ParserType)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    // Parsers declaring their columns get the headers of every file validated before import
+    if columnParser, ok := parser.(ColumnParser); ok {
+        config.Columns = columnParser.Columns()
+    }
+
+    // Batches building on each other are saved in the order they were read
+    if sequentialSaver, ok := parser.(SequentialSaver); ok && sequentialSaver.SavesSequentially() && config.Savers > 1 {
+        log.Printf("Parser %s saves its batches in order, using a single saver", config.ParserType)
+        config.Savers = 1
+    }
+
+    if config.Bulk {
+        if _, ok := parser.(anyBulkSaver); !ok {
+            log.Printf("Parser %s has no bulk load, saving batches row by row", config.ParserType)
+            config.Bulk = false
+        }
+    }
+
+    // Sheets given on the command line override the sheets declared by the parser
+    if *sheet != "" {
+        config.Sheets = []string{*sheet}
+    } else if sheetParser, ok := parser.(SheetParser); ok {
+        config.Sheets = sheetParser.Sheets()
+    }
+
+    // Rows failing to map, process or save are written to the rejects file
+    var rejects *RejectsFile
+    if *rejectsPath != "" {
+        rejects, err = createRejectsFile(*rejectsPath)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    // The first SIGINT or SIGTERM stops reading rows and lets the batches being saved finish,
+    // a second one rolls them back
+    ctx, saveCtx, stop := interruptContexts()
+    defer stop()
+
+    // A dry run checks the files without connecting to the database
+    if *dryRunFlag {
+        report, err := dryRun(ctx, parser, config, rejects)
+        if rejects != nil {
+            if closeErr := rejects.Close(); closeErr != nil && err == nil {
+                err = closeErr
+            }
+        }
+        if err != nil {
+            log.Fatal(err)
+        }
+        report.Print()
+        if rejects != nil {
+            fmt.Printf("Rejected rows written to %s: %d\n", rejects.Path, rejects.Count)
+        }
+        if !report.OK() {
+            os.Exit(1)
+        }
+        return
     }
 
     // Connect to database
@@ -47,96 +200,254 @@ func main() {
     }
     defer db.Close()
 
-    // Get the appropriate parser based on type
-    parser, err := createParser(config.ParserType)
+    // Files imported successfully before are skipped unless forced
+    matchFiles := isInputFile
+    if fileMatcher, ok := parser.(FileMatcher); ok {
+        matchFiles, err = fileMatcher.MatchFiles(config)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+    files, err := checksumInputFiles(config, matchFiles)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if !*force {
+        if err := findImportedFiles(db, config.ParserType, files); err != nil {
+            log.Fatal(err)
+        }
+        config.SkipFiles = make(map[inputFile]bool)
+        for _, file := range files {
+            if file.ImportedBy > 0 {
+                log.Printf("Skipping %s, already imported by run %d (use -force to import it again)", file.File.Name(), file.ImportedBy)
+                config.SkipFiles[file.File] = true
+            }
+        }
+    }
+
+    // A resumed import skips the rows committed by the run it continues
+    if *resume {
+        config.Resume, err = findResumeCheckpoint(db, config, files)
+        if err != nil {
+            log.Fatal(err)
+        }
+        if config.Resume.Rows > 0 {
+            log.Printf("Resuming import run %d %s", config.Resume.RunID, config.Resume)
+        } else {
+            log.Printf("Import run %d committed no rows, resuming it from the first row", config.Resume.RunID)
+        }
+    }
+
+    run, err := startImportRun(db, config, files)
     if err != nil {
         log.Fatal(err)
     }
 
+    if len(files) > 0 && len(config.SkipFiles) == len(files) {
+        if rejects != nil {
+            rejects.Close()
+        }
+        if err := run.finish(db, importSkipped, 0, 0, 0, nil); err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("All files were already imported, nothing to do (import run %d)\n", run.ID)
+        return
+    }
+
     // Common file reading and chunking logic
-    totalProcessed, totalInserted, totalErrors := readAndProcessFile(db, parser, config)
+    totalProcessed, totalInserted, totalErrors, err := readAndProcessFile(ctx, saveCtx, db, run, parser, config, rejects)
+    if rejects != nil {
+        if closeErr := rejects.Close(); closeErr != nil && err == nil {
+            err = closeErr
+        }
+    }
 
-    // Print summary statistics
+    status := importSucceeded
+    switch {
+    case ctx.Err() != nil:
+        status = importInterrupted
+    case err != nil:
+        status = importFailed
+    case totalErrors > 0:
+        status = importPartial
+    }
+    if finishErr := run.finish(db, status, totalProcessed, totalInserted, totalErrors, err); finishErr != nil {
+        log.Print(finishErr)
+    }
+
+    // Print summary statistics, also of a failed or interrupted import
     fmt.Println("\n*** Import Summary ***")
+    fmt.Printf("Import run: %d\n", run.ID)
+    if config.Resume.RunID > 0 {
+        fmt.Printf("Resumed import run %d, rows skipped: %d\n", config.Resume.RunID, config.Resume.Rows)
+    }
     fmt.Printf("Parser type: %s\n", config.ParserType)
     fmt.Printf("Path: %s\n", config.FilePath)
+    fmt.Printf("Files imported: %d, skipped: %d\n", len(files)-len(config.SkipFiles), len(config.SkipFiles))
     fmt.Printf("Total rows processed: %d\n", totalProcessed)
     fmt.Printf("Total entries inserted/updated: %d\n", totalInserted)
     fmt.Printf("Total errors: %d\n", totalErrors)
-    fmt.Println("Import process completed!")
+    if rejects != nil {
+        fmt.Printf("Rejected rows written to %s: %d\n", rejects.Path, rejects.Count)
+    }
+
+    switch status {
+    case importSucceeded:
+        fmt.Println("Import process completed!")
+        return
+    case importInterrupted:
+        fmt.Println("Import interrupted, the batches saved before the interruption are kept")
+        if err != nil {
+            fmt.Printf("Error: %v\n", err)
+        }
+        fmt.Println("Run again with -resume to continue after the last committed row")
+    default:
+        fmt.Printf("Import failed: %v\n", err)
+        fmt.Println("Run again with -resume to continue after the last committed row")
+    }
+
+    // Deferred calls do not run on exit
+    stop()
+    db.Close()
+    os.Exit(1)
 }
 
-// createParser returns the appropriate parser based on the type
-func createParser(parserType string) (Parser, error) {
+// createParser returns the appropriate parser based on the type, adapted to entries of any type
+func createParser(parserType string) (AnyParser, error) {
     switch parserType {
     case "nomenclature":
-        return &NomenclatureParser{}, nil
+        return adaptParser[NomenclatureEntry](&NomenclatureParser{}), nil
     case "declarable_codes":
-		return &DeclarableCodesParser{}, nil
+		return adaptParser[DeclarableCodesEntry](&DeclarableCodesParser{}), nil
+    case "measures":
+        return adaptParser[MeasureEntry](&MeasuresParser{}), nil
+    case "geographical_areas":
+        return adaptParser[GeographicalAreaEntry](&GeographicalAreasParser{}), nil
+    case "additional_codes":
+        return adaptParser[AdditionalCodeEntry](&AdditionalCodesParser{}), nil
+    case "footnotes":
+        return adaptParser[FootnoteEntry](&FootnotesParser{}), nil
+    case "certificates":
+        return adaptParser[CertificateEntry](&CertificatesParser{}), nil
+    case "measure_conditions":
+        return adaptParser[MeasureConditionEntry](&MeasureConditionsParser{}), nil
+    case "quotas":
+        return adaptParser[QuotaEntry](&QuotasParser{}), nil
+    case "quota_balances":
+        return adaptParser[QuotaBalanceEntry](&QuotaBalancesParser{}), nil
+    case "taric_deltas":
+        return adaptParser[TaricTransaction](&TaricDeltasParser{}), nil
     default:
         return nil, fmt.Errorf("unknown parser type: %s", parserType)
     }
 }
 
-// readAndProcessFile handles the common logic of reading data and processing entries
-func readAndProcessFile(db *sql.DB, parser Parser, config ParserConfig) (int, int, int) {
-    // Initialize counters and batch
+// readAndProcessFile handles the common logic of reading data and processing entries.
+// Rows are mapped on config.Workers goroutines and batches saved on config.Savers goroutines; the batches are
+// the same whatever the number of workers, as rows are passed on in the order they were read.
+// Once ctx is cancelled no more rows are read and no more batches started, while the batches being saved are
+// finished, or rolled back if saveCtx is cancelled too. Rejected rows are written to rejects, if it is not nil.
+// The checkpoint of run is recorded after each batch committed with every batch before it, and the rows up to
+// config.Resume are skipped.
+// It returns the counts of processed, inserted and errored rows, and the error which stopped the import, if any.
+func readAndProcessFile(ctx, saveCtx context.Context, db *sql.DB, run *importRun, parser AnyParser, config ParserConfig, rejects *RejectsFile) (int, int, int, error) {
+    // Initialize counters
     totalProcessed := 0
-    totalInserted := 0
     totalErrors := 0
-    rowCount := 0
-    entries := make([]interface{}, 0, config.ChunkSize)
-    rowNumber := 0
+
+    // reject records a row left out of the import
+    reject := func(row RowData, rowNumber int, stage string, err error) error {
+        if rejects == nil {
+            return nil
+        }
+        return rejects.Write(newRejectedRow(row, rowNumber, stage, err))
+    }
+
+    // Reading stops when ctx is cancelled, or when the import fails
+    readCtx, cancelRead := context.WithCancel(ctx)
+    defer cancelRead()
 
     // Get channel of rows from the parser
-    rowsChan, err := parser.ReadRows(config)
+    rowsChan, err := parser.ReadRows(readCtx, config)
     if err != nil {
-        log.Fatalf("Failed to read file: %v", err)
+        return 0, 0, 0, fmt.Errorf("failed to read file: %v", err)
     }
 
-    // Process rows
-    for row := range rowsChan {
-        rowNumber++
+    // Batches are saved with COPY when selected
+    save := func(db *sql.DB, entries []interface{}) (int, error) {
+        return parser.SaveEntries(saveCtx, db, entries)
+    }
+    if bulkSaver, ok := parser.(anyBulkSaver); ok && config.Bulk {
+        save = func(db *sql.DB, entries []interface{}) (int, error) {
+            return bulkSaver.BulkSaveEntries(saveCtx, db, entries)
+        }
+    }
 
-        // Parse the row using the specific parser
-        entry, err := parser.MapRow(row)
-        if err != nil {
-            log.Printf("Error parsing row %d: %v", rowNumber, err)
-            totalErrors++
-            continue
+    // checkpoint records the last committed row, from where the import can be resumed
+    checkpoint := func(row RowData, rowNumber int) error {
+        return run.saveCheckpoint(db, newImportCheckpoint(run.ID, row, rowNumber))
+    }
+
+    savers := newBatchSavers(db, save, config.Savers, reject, checkpoint)
+    batch := newSaveBatch(config.ChunkSize)
+
+    // Process rows in the order they were read, after those committed by the resumed run
+    mappedChan := mapRows(parser, rowsChan, config.Workers, config.Resume.Rows)
+    for mapped := range mappedChan {
+        // Rows mapped after an interruption are not saved
+        if ctx.Err() != nil {
+            drainMappedRows(mappedChan)
+            break
         }
 
-        // Process the entry (e.g., calculate derived fields)
-        if err := parser.ProcessEntry(&entry); err != nil {
-            log.Printf("Error processing row %d: %v", rowNumber, err)
+        if mapped.Err != nil {
+            switch mapped.Stage {
+            case stageRead:
+                log.Printf("Error reading %s: %v", rowLocation(mapped.Row, mapped.RowNumber), mapped.Err)
+            case stageMap:
+                log.Printf("Error parsing %s: %v", rowLocation(mapped.Row, mapped.RowNumber), mapped.Err)
+            default:
+                log.Printf("Error processing %s: %v", rowLocation(mapped.Row, mapped.RowNumber), mapped.Err)
+            }
             totalErrors++
+            if err := reject(mapped.Row, mapped.RowNumber, mapped.Stage, mapped.Err); err != nil {
+                cancelRead()
+                drainMappedRows(mappedChan)
+                totalInserted, failed, _ := savers.Wait()
+                return totalProcessed, totalInserted, totalErrors + failed, err
+            }
             continue
         }
 
-        entries = append(entries, entry)
-        rowCount++
+        batch.add(mapped)
         totalProcessed++
 
-        // Save in batches when we reach the chunk size
-        if rowCount >= config.ChunkSize {
-            insertedCount, err := parser.SaveEntries(db, entries)
-            if err != nil {
-                log.Fatalf("Failed to insert entries: %v", err)
+        // Save in batches when we reach the chunk size, waiting while every saver is busy
+        if len(batch.Entries) >= config.ChunkSize {
+            if !savers.Save(batch) {
+                cancelRead()
+                drainMappedRows(mappedChan)
+                break
             }
-            totalInserted += insertedCount
-            entries = entries[:0] // Clear the slice while keeping capacity
-            rowCount = 0
+            batch = newSaveBatch(config.ChunkSize)
         }
     }
 
-    // Save any remaining entries
-    if len(entries) > 0 {
-        insertedCount, err := parser.SaveEntries(db, entries)
-        if err != nil {
-            log.Fatalf("Failed to insert entries: %v", err)
-        }
-        totalInserted += insertedCount
+    // Save any remaining entries, unless the import was interrupted
+    if len(batch.Entries) > 0 && ctx.Err() == nil {
+        savers.Save(batch)
+    }
+
+    // The rows of failed batches are rejected too
+    totalInserted, failed, err := savers.Wait()
+    return totalProcessed, totalInserted, totalErrors + failed, err
+}
+
+// rowLocation describes where a row came from, falling back to its position in the import
+func rowLocation(row RowData, rowNumber int) string {
+    if excelRow, ok := row.(ExcelRow); ok && excelRow.Row > 0 {
+        return excelRow.Location()
     }
 
-    return totalProcessed, totalInserted, totalErrors
-}
\ No newline at end of file
+    return fmt.Sprintf("row %d", rowNumber)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MeasureConditionEntry represents a single row from the TARIC measure conditions export.
+// The measure is identified by the same columns that make a measure unique in the measures export.
+type MeasureConditionEntry struct {
+	GoodsCode        string    // Goods code of the measure, normalized to 10 digits + 2-digit suffix
+	MeasureType      string    // Measure type code of the measure (e.g. "410" Veterinary control)
+	GeographicalArea string    // Origin code of the measure
+	AdditionalCode   string    // Additional code of the measure, empty if the measure has none
+	OrderNumber      string    // Tariff quota order number of the measure, empty if the measure has none
+	StartDate        time.Time // Validity start date of the measure
+	ConditionCode    string    // Condition code (e.g. "B" presentation of a certificate, "V" entry price)
+	SequenceNumber   int       // Order in which the conditions of the same code are evaluated
+	CertificateType  string    // Type of the required certificate, empty if the condition requires no document
+	CertificateCode  string    // Code of the required certificate, empty if the condition requires no document
+	ActionCode       string    // Action taken when the condition is met (e.g. "01" apply the measure, "04" import not allowed)
+	DutyAmount       *float64  // Reference amount of the condition (e.g. entry price), nil if the condition has none
+	Unit             string    // Unit of the reference amount (e.g. "EUR / 100 kg")
+}
+
+// MeasureConditionsParser implements the Parser interface for measure condition files
+type MeasureConditionsParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the measure conditions export
+func (p *MeasureConditionsParser) Columns() []Column {
+	return []Column{
+		goodsCodeColumn,
+		{Name: "Meas. type code", Aliases: []string{"Measure type code"}},
+		{Name: "Origin code"},
+		{Name: "Add code", Aliases: []string{"Additional code"}},
+		{Name: "Order No.", Aliases: []string{"Order number"}},
+		startDateColumn,
+		{Name: "Condition code"},
+		{Name: "Sequence no", Aliases: []string{"Sequence number"}},
+		{Name: "Certificate"},
+		{Name: "Action code"},
+		{Name: "Duty amount", Optional: true},
+		{Name: "Unit", Optional: true},
+	}
+}
+
+// MapRow converts an ExcelRow of the measure conditions export to a MeasureConditionEntry.
+func (p *MeasureConditionsParser) MapRow(rowData RowData) (MeasureConditionEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return MeasureConditionEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	goodsCode, err := normalizeGoodsCode(row.Value("Goods code"))
+	if err != nil {
+		return MeasureConditionEntry{}, err
+	}
+
+	entry := MeasureConditionEntry{
+		GoodsCode:        goodsCode,
+		MeasureType:      strings.TrimSpace(row.Value("Meas. type code")),
+		GeographicalArea: strings.TrimSpace(row.Value("Origin code")),
+		AdditionalCode:   strings.TrimSpace(row.Value("Add code")),
+		ConditionCode:    strings.TrimSpace(row.Value("Condition code")),
+		ActionCode:       strings.TrimSpace(row.Value("Action code")),
+		Unit:             strings.TrimSpace(row.Value("Unit")),
+	}
+
+	if entry.MeasureType == "" {
+		return MeasureConditionEntry{}, fmt.Errorf("measure type code is required")
+	}
+	if entry.GeographicalArea == "" {
+		return MeasureConditionEntry{}, fmt.Errorf("origin code is required")
+	}
+	if entry.ConditionCode == "" {
+		return MeasureConditionEntry{}, fmt.Errorf("condition code is required")
+	}
+
+	if strings.TrimSpace(row.Value("Order No.")) != "" {
+		entry.OrderNumber, err = normalizeOrderNumber(row.Value("Order No."))
+		if err != nil {
+			return MeasureConditionEntry{}, err
+		}
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return MeasureConditionEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.SequenceNumber, err = strconv.Atoi(strings.TrimSpace(row.Value("Sequence no")))
+	if err != nil {
+		return MeasureConditionEntry{}, fmt.Errorf("invalid sequence number %q: %v", row.Value("Sequence no"), err)
+	}
+
+	if strings.TrimSpace(row.Value("Certificate")) != "" {
+		entry.CertificateType, entry.CertificateCode, err = splitCertificate("", row.Value("Certificate"))
+		if err != nil {
+			return MeasureConditionEntry{}, err
+		}
+	}
+
+	entry.DutyAmount, err = parseOptionalDecimal(row.Value("Duty amount"))
+	if err != nil {
+		return MeasureConditionEntry{}, fmt.Errorf("invalid duty amount %q: %v", row.Value("Duty amount"), err)
+	}
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for measure conditions
+func (p *MeasureConditionsParser) ProcessEntry(entry *MeasureConditionEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of measure condition entries to the database
+func (p *MeasureConditionsParser) SaveEntries(ctx context.Context, db *sql.DB, entries []MeasureConditionEntry) (int, error) {
+	return insertMeasureConditionEntries(ctx, db, entries)
+}
+
+func insertMeasureConditionEntries(ctx context.Context, db *sql.DB, entries []MeasureConditionEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	conditionStmt, err := tx.Prepare(`
+		INSERT INTO measure_conditions
+		(measure_id, condition_code, sequence_number, certificate_type, certificate_code, action_code, duty_amount, unit)
+		SELECT id, $7, $8, $9, $10, $11, $12, $13 FROM measures
+		WHERE goods_code = $1 AND measure_type_code = $2 AND geographical_area_code = $3
+		AND additional_code = $4 AND order_number = $5 AND start_date = $6
+		ON CONFLICT (measure_id, condition_code, sequence_number)
+		DO UPDATE SET certificate_type = $9, certificate_code = $10, action_code = $11,
+			duty_amount = $12, unit = $13, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure condition statement: %v", err)
+	}
+	defer conditionStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		result, err := conditionStmt.Exec(
+			entry.GoodsCode,
+			entry.MeasureType,
+			entry.GeographicalArea,
+			entry.AdditionalCode,
+			entry.OrderNumber,
+			entry.StartDate,
+			entry.ConditionCode,
+			entry.SequenceNumber,
+			entry.CertificateType,
+			entry.CertificateCode,
+			entry.ActionCode,
+			entry.DutyAmount,
+			entry.Unit,
+		)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert measure condition for %s: %v", entry.GoodsCode, err)
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			log.Printf("no measure %s %s found for goods code: %s starting %s", entry.MeasureType, entry.GeographicalArea, entry.GoodsCode, entry.StartDate.Format("2006-01-02"))
+			continue
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
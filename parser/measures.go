@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MeasureEntry represents a single row from the TARIC measures export
+type MeasureEntry struct {
+	GoodsCode              string     // Goods code the measure applies to, normalized to 10 digits + 2-digit suffix
+	AdditionalCode         string     // Additional code type and code (e.g. "C999"), empty if the measure has none
+	OrderNumber            string     // Tariff quota order number (e.g. "091104"), empty if the measure has none
+	StartDate              time.Time  // Validity start date of the measure
+	EndDate                *time.Time // Validity end date of the measure (can be empty)
+	GeographicalArea       string     // Geographical area code: a country (e.g. "CN") or a group (e.g. "1011" ERGA OMNES)
+	ExcludedAreas          []string   // Geographical area codes excluded from a group (e.g. "CN"), empty if none are
+	MeasureType            string     // Measure type code (e.g. "103" Third country duty)
+	MeasureTypeDescription string     // Description of the measure type
+	Regulation             string     // Legal base of the measure (e.g. "R2658/87")
+	DutyExpression         string     // Duty expression (e.g. "12.80 % + 176.80 EUR / 100 kg")
+}
+
+// MeasuresParser implements the Parser interface for TARIC measures files
+type MeasuresParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the measures export
+func (p *MeasuresParser) Columns() []Column {
+	return []Column{
+		goodsCodeColumn,
+		{Name: "Add code", Aliases: []string{"Additional code"}},
+		{Name: "Order No.", Aliases: []string{"Order number"}},
+		startDateColumn,
+		endDateColumn,
+		{Name: "RED_IND", Optional: true},
+		{Name: "Origin", Optional: true},
+		{Name: "Origin code"},
+		{Name: "Excluded origins", Aliases: []string{"Excluded origin codes", "Origin excluded"}, Optional: true},
+		{Name: "Meas. type code", Aliases: []string{"Measure type code"}},
+		{Name: "Measure type"},
+		{Name: "Legal base", Aliases: []string{"Regulation"}},
+		{Name: "Duty", Aliases: []string{"Duty expression"}},
+	}
+}
+
+// MapRow converts an ExcelRow of the measures export to a MeasureEntry.
+func (p *MeasuresParser) MapRow(rowData RowData) (MeasureEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return MeasureEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	goodsCode, err := normalizeGoodsCode(row.Value("Goods code"))
+	if err != nil {
+		return MeasureEntry{}, err
+	}
+
+	entry := MeasureEntry{
+		GoodsCode:              goodsCode,
+		AdditionalCode:         strings.TrimSpace(row.Value("Add code")),
+		GeographicalArea:       strings.TrimSpace(row.Value("Origin code")),
+		MeasureType:            strings.TrimSpace(row.Value("Meas. type code")),
+		MeasureTypeDescription: strings.TrimSpace(row.Value("Measure type")),
+		Regulation:             strings.TrimSpace(row.Value("Legal base")),
+		DutyExpression:         strings.TrimSpace(row.Value("Duty")),
+	}
+
+	entry.ExcludedAreas = strings.FieldsFunc(row.Value("Excluded origins"), func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n'
+	})
+
+	if strings.TrimSpace(row.Value("Order No.")) != "" {
+		entry.OrderNumber, err = normalizeOrderNumber(row.Value("Order No."))
+		if err != nil {
+			return MeasureEntry{}, err
+		}
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return MeasureEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return MeasureEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	if entry.MeasureType == "" {
+		return MeasureEntry{}, fmt.Errorf("measure type code is required")
+	}
+	if entry.GeographicalArea == "" {
+		return MeasureEntry{}, fmt.Errorf("origin code is required")
+	}
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for measures
+func (p *MeasuresParser) ProcessEntry(entry *MeasureEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of measure entries to the database
+func (p *MeasuresParser) SaveEntries(ctx context.Context, db *sql.DB, entries []MeasureEntry) (int, error) {
+	return insertMeasureEntries(ctx, db, entries)
+}
+
+func insertMeasureEntries(ctx context.Context, db *sql.DB, entries []MeasureEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	goodsCodes := make([]string, len(entries))
+	for i, entry := range entries {
+		goodsCodes[i] = entry.GoodsCode
+	}
+
+	// Measures are linked to the validity period of the goods code they start in
+	periods, err := findNomenclaturePeriods(tx, goodsCodes)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	typeStmt, err := tx.Prepare(`
+		INSERT INTO measure_types (code, description)
+		VALUES ($1, $2)
+		ON CONFLICT (code) DO UPDATE SET description = COALESCE(NULLIF($2, ''), measure_types.description), updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure type statement: %v", err)
+	}
+	defer typeStmt.Close()
+
+	measureStmt, err := tx.Prepare(`
+		INSERT INTO measures
+		(nomenclature_id, goods_code, measure_type_code, geographical_area_code, additional_code,
+		 order_number, regulation, start_date, end_date, duty_expression)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (goods_code, measure_type_code, geographical_area_code, additional_code, order_number, start_date)
+		DO UPDATE SET nomenclature_id = $1, regulation = $7, end_date = $9, duty_expression = $10, updated_at = NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure statement: %v", err)
+	}
+	defer measureStmt.Close()
+
+	// The excluded areas of a measure are replaced by those of its latest row
+	clearExclusionsStmt, err := tx.Prepare(`DELETE FROM measure_excluded_areas WHERE measure_id = $1`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure exclusions delete statement: %v", err)
+	}
+	defer clearExclusionsStmt.Close()
+
+	exclusionStmt, err := tx.Prepare(`
+		INSERT INTO measure_excluded_areas (measure_id, geographical_area_code)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare measure exclusion statement: %v", err)
+	}
+	defer exclusionStmt.Close()
+
+	// Save each measure type once per batch
+	savedTypes := make(map[string]bool)
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		// A measure left out would not be counted, so the batch fails and its rows are rejected
+		nomenclatureID, exists := nomenclatureIDOn(periods[entry.GoodsCode], entry.StartDate)
+		if !exists {
+			tx.Rollback()
+			return successCount, fmt.Errorf("no nomenclature found for goods code %s of measure %s starting %s, "+
+				"import the nomenclature first", entry.GoodsCode, entry.MeasureType, entry.StartDate.Format("2006-01-02"))
+		}
+
+		if !savedTypes[entry.MeasureType] {
+			if _, err := typeStmt.Exec(entry.MeasureType, entry.MeasureTypeDescription); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert measure type %s: %v", entry.MeasureType, err)
+			}
+			savedTypes[entry.MeasureType] = true
+		}
+
+		var measureID int
+		err = measureStmt.QueryRow(
+			nomenclatureID,
+			entry.GoodsCode,
+			entry.MeasureType,
+			entry.GeographicalArea,
+			entry.AdditionalCode,
+			entry.OrderNumber,
+			entry.Regulation,
+			entry.StartDate,
+			entry.EndDate,
+			entry.DutyExpression,
+		).Scan(&measureID)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert measure for %s: %v", entry.GoodsCode, err)
+		}
+
+		if _, err := clearExclusionsStmt.Exec(measureID); err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to clear excluded areas of measure for %s: %v", entry.GoodsCode, err)
+		}
+		for _, area := range entry.ExcludedAreas {
+			if _, err := exclusionStmt.Exec(measureID, area); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert excluded area %s of measure for %s: %v", area, entry.GoodsCode, err)
+			}
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
+
+// Validate checks that no measure ends before it starts
+func (p *MeasuresParser) Validate(entries []MeasureEntry) []ValidationProblem {
+	var problems []ValidationProblem
+	for i, entry := range entries {
+		if entry.EndDate != nil && entry.EndDate.Before(entry.StartDate) {
+			problems = append(problems, ValidationProblem{Index: i,
+				Message: fmt.Sprintf("measure %s of goods code %s for %s ends on %s, before it starts on %s",
+					entry.MeasureType, entry.GoodsCode, entry.GeographicalArea,
+					entry.EndDate.Format("2006-01-02"), entry.StartDate.Format("2006-01-02"))})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,59 @@
+-- Table to store TARIC measure types (e.g. 103 Third country duty, 142 Tariff preference)
+CREATE TABLE measure_types (
+    code VARCHAR(3) PRIMARY KEY,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table to store TARIC duty measures applied to goods codes
+CREATE TABLE measures (
+    id SERIAL PRIMARY KEY,
+    nomenclature_id INTEGER NOT NULL REFERENCES nomenclatures(id) ON DELETE CASCADE,
+    goods_code VARCHAR(13) NOT NULL,
+    measure_type_code VARCHAR(3) NOT NULL REFERENCES measure_types(code),
+    geographical_area_code VARCHAR(4) NOT NULL,
+    additional_code VARCHAR(4) NOT NULL DEFAULT '',
+    order_number VARCHAR(6) NOT NULL DEFAULT '',
+    regulation TEXT NOT NULL DEFAULT '',
+    start_date DATE NOT NULL,
+    end_date DATE,
+    duty_expression TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(goods_code, measure_type_code, geographical_area_code, additional_code, order_number, start_date)
+);
+
+-- Table to store the geographical areas excluded from a measure defined for a country group,
+-- e.g. a measure for ERGA OMNES not applying to goods originating in China
+CREATE TABLE measure_excluded_areas (
+    measure_id INTEGER NOT NULL REFERENCES measures(id) ON DELETE CASCADE,
+    geographical_area_code VARCHAR(4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (measure_id, geographical_area_code)
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_measures_nomenclature_id ON measures(nomenclature_id);
+CREATE INDEX idx_measures_goods_code ON measures(goods_code);
+CREATE INDEX idx_measures_geographical_area_code ON measures(geographical_area_code);
+CREATE INDEX idx_measures_order_number ON measures(order_number);
+CREATE INDEX idx_measure_excluded_areas_code ON measure_excluded_areas(geographical_area_code);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_measure_types_modtime
+BEFORE UPDATE ON measure_types
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_measures_modtime
+BEFORE UPDATE ON measures
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -72,49 +74,59 @@ type NomenclatureEntry struct {
 
 // NomenclatureParser implements the Parser interface for nomenclature files
 type NomenclatureParser struct {
-    BaseExcelParser // Embed the BaseExcelParser to inherit ReadRows
+    BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the nomenclature export
+func (p *NomenclatureParser) Columns() []Column {
+    return []Column{
+        goodsCodeColumn,
+        startDateColumn,
+        endDateColumn,
+        languageColumn,
+        {Name: "Hier. Pos.", Aliases: []string{"Hier. poz."}},
+        {Name: "Indent", Aliases: []string{"Įtrauka"}},
+        descriptionColumn,
+        {Name: "Descr. start date", Aliases: []string{"Aprašymo pradžios data"}},
+    }
 }
 
 // MapRow now expects an ExcelRow and converts it to a NomenclatureEntry
-func (p *NomenclatureParser) MapRow(rowData RowData) (interface{}, error) {
+func (p *NomenclatureParser) MapRow(rowData RowData) (NomenclatureEntry, error) {
     row, ok := rowData.(ExcelRow)
     if !ok {
-        return nil, fmt.Errorf("expected ExcelRow, got %T", rowData)
-    }
-    
-    cells := row.Cells
-    if len(cells) < 8 {
-        return nil, fmt.Errorf("insufficient columns: need at least 8 columns")
+        return NomenclatureEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
     }
     
     entry := NomenclatureEntry{}
-    entry.GoodsCode = cells[0]
+    entry.GoodsCode = row.Value("Goods code")
     
     // Parse dates - handle empty dates
-    if cells[1] != "" {
-        startDate, err := time.Parse("02-01-2006", cells[1])
+    if value := row.Value("Start date"); value != "" {
+        startDate, err := time.Parse("02-01-2006", value)
         if err != nil {
-            return nil, fmt.Errorf("invalid start date format: %v", err)
+            return NomenclatureEntry{}, fmt.Errorf("invalid start date format: %v", err)
         }
         entry.StartDate = startDate
     }
 
-    if cells[2] != "" {
-        endDate, err := time.Parse("02-01-2006", cells[2])
+    if value := row.Value("End date"); value != "" {
+        endDate, err := time.Parse("02-01-2006", value)
         if err != nil {
-            return nil, fmt.Errorf("invalid end date format: %v", err)
+            return NomenclatureEntry{}, fmt.Errorf("invalid end date format: %v", err)
         }
         entry.EndDate = &endDate
     }
     
-    entry.Language = cells[3]
+    entry.Language = row.Value("Language")
 
     // Parse hier_pos
-    hierPos, err := strconv.Atoi(cells[4])
+    hierPosValue := row.Value("Hier. Pos.")
+    hierPos, err := strconv.Atoi(hierPosValue)
     if err != nil {
-        hierPosFloat, floatErr := strconv.ParseFloat(cells[4], 64)
+        hierPosFloat, floatErr := strconv.ParseFloat(hierPosValue, 64)
         if floatErr != nil {
-            return nil, fmt.Errorf("invalid Hier. Pos. format: %v", err)
+            return NomenclatureEntry{}, fmt.Errorf("invalid Hier. Pos. format: %v", err)
         }
         hierPos = int(hierPosFloat)
     }
@@ -122,16 +134,16 @@ func (p *NomenclatureParser) MapRow(rowData RowData) (interface{}, error) {
     entry.HierPos = hierPos
 
     // Parse indent
-    indent := countDashes(cells[5])
+    indent := countDashes(row.Value("Indent"))
     entry.Indent = indent
 
-    entry.Description = cells[6]
+    entry.Description = row.Value("Description")
 
     // Parse description start date
-    if cells[7] != "" {
-        descrStartDate, err := time.Parse("02-01-2006", cells[7])
+    if value := row.Value("Descr. start date"); value != "" {
+        descrStartDate, err := time.Parse("02-01-2006", value)
         if err != nil {
-            return nil, fmt.Errorf("invalid description start date format: %v", err)
+            return NomenclatureEntry{}, fmt.Errorf("invalid description start date format: %v", err)
         }
         entry.DescrStartDate = descrStartDate
     }
@@ -140,56 +152,19 @@ func (p *NomenclatureParser) MapRow(rowData RowData) (interface{}, error) {
 }
 
 // ProcessEntry calculates additional fields for a nomenclature entry
-func (p *NomenclatureParser) ProcessEntry(entryInterface *interface{}) error {
-    // First, dereference the pointer to get the actual interface{} value
-    entryValue := *entryInterface
-    
-    // Now we can perform type assertions on the actual interface value
-    switch entry := entryValue.(type) {
-    case *NomenclatureEntry:
-        // It's already a pointer to NomenclatureEntry
-        hierPath, err := getHierarchyPath(entry.GoodsCode, entry.HierPos)
-        if err != nil {
-            return fmt.Errorf("error getting hierarchy path: %v", err)
-        }
-        entry.HierarchyPath = hierPath
-        
-    case NomenclatureEntry:
-        // It's a value type, need to create a pointer and update the interface
-        newEntry := entry // Create a copy
-        hierPath, err := getHierarchyPath(newEntry.GoodsCode, newEntry.HierPos)
-        if err != nil {
-            return fmt.Errorf("error getting hierarchy path: %v", err)
-        }
-        newEntry.HierarchyPath = hierPath
-        
-        // Update the original interface pointer to point to our new entry
-        *entryInterface = newEntry
-        
-    default:
-        return fmt.Errorf("unexpected entry type: %T", entryValue)
+func (p *NomenclatureParser) ProcessEntry(entry *NomenclatureEntry) error {
+    hierPath, err := getHierarchyPath(entry.GoodsCode, entry.HierPos)
+    if err != nil {
+        return fmt.Errorf("error getting hierarchy path: %v", err)
     }
-    
+    entry.HierarchyPath = hierPath
+
     return nil
 }
 
 // SaveEntries saves a batch of nomenclature entries to the database
-func (p *NomenclatureParser) SaveEntries(db *sql.DB, entriesInterface []interface{}) (int, error) {
-    // Convert generic entries to NomenclatureEntry
-    entries := make([]NomenclatureEntry, len(entriesInterface))
-    for i, e := range entriesInterface {
-        switch entry := e.(type) {
-        case NomenclatureEntry:
-            entries[i] = entry
-        case *NomenclatureEntry:
-            entries[i] = *entry
-        default:
-            return 0, fmt.Errorf("invalid entry type at index %d: %T", i, e)
-        }
-    }
-    
-    // Use the existing insertEntries function
-    return insertEntries(db, entries)
+func (p *NomenclatureParser) SaveEntries(ctx context.Context, db *sql.DB, entries []NomenclatureEntry) (int, error) {
+    return insertEntries(ctx, db, entries)
 }
 
 func countDashes(s string) int {
@@ -229,8 +204,8 @@ func getHierarchyPath(goodsCode string, hierPos int) (string, error) {
 }
 
 // Reuse the insertEntries function from the original code
-func insertEntries(db *sql.DB, entries []NomenclatureEntry) (int, error) {
-    tx, err := db.Begin()
+func insertEntries(ctx context.Context, db *sql.DB, entries []NomenclatureEntry) (int, error) {
+    tx, err := db.BeginTx(ctx, nil)
     if err != nil {
         return 0, fmt.Errorf("failed to begin transaction: %v", err)
     }
@@ -245,8 +220,8 @@ func insertEntries(db *sql.DB, entries []NomenclatureEntry) (int, error) {
         INSERT INTO nomenclatures 
         (goods_code, start_date, end_date, hierarchy_path, indent) 
         VALUES ($1, $2, $3, $4::ltree, $5)
-        ON CONFLICT (goods_code) 
-        DO UPDATE SET hierarchy_path = $4::ltree, indent = $5, updated_at = NOW()
+        ON CONFLICT (goods_code, start_date) 
+        DO UPDATE SET end_date = $3, hierarchy_path = $4::ltree, indent = $5, updated_at = NOW()
         RETURNING id
     `)
 
@@ -260,8 +235,8 @@ func insertEntries(db *sql.DB, entries []NomenclatureEntry) (int, error) {
         INSERT INTO nomenclature_descriptions
         (nomenclature_id, language, description, descr_start_date)
         VALUES ($1, $2, $3, $4)
-        ON CONFLICT (nomenclature_id, language)
-        DO UPDATE SET description = $3, descr_start_date = $4, updated_at = NOW()
+        ON CONFLICT (nomenclature_id, language, descr_start_date)
+        DO UPDATE SET description = $3, updated_at = NOW()
     `)
     if err != nil {
         tx.Rollback()
@@ -311,4 +286,93 @@ func insertEntries(db *sql.DB, entries []NomenclatureEntry) (int, error) {
     }
     
     return successCount, nil
-}
\ No newline at end of file
+}
+// Validate checks the hierarchy of the nomenclature of every language in the import: every code below a chapter
+// has a parent, chapters and headings have no indent while lower codes do, the Hier. Pos. of a code covers its
+// significant digits and no validity period ends before it starts
+func (p *NomenclatureParser) Validate(entries []NomenclatureEntry) []ValidationProblem {
+    var problems []ValidationProblem
+    problem := func(index int, format string, args ...interface{}) {
+        problems = append(problems, ValidationProblem{Index: index, Message: fmt.Sprintf(format, args...)})
+    }
+
+    codes := make([]string, len(entries)) // Goods codes in the "0101210000 80" form
+    byLanguage := make(map[string][]int)
+    for i, entry := range entries {
+        if entry.EndDate != nil && entry.EndDate.Before(entry.StartDate) {
+            problem(i, "goods code %s ends on %s, before it starts on %s",
+                entry.GoodsCode, entry.EndDate.Format("2006-01-02"), entry.StartDate.Format("2006-01-02"))
+        }
+
+        code, err := normalizeGoodsCode(entry.GoodsCode)
+        if err != nil {
+            problem(i, "%v", err)
+            continue
+        }
+        if level := hierarchyLevel(code); level > entry.HierPos {
+            problem(i, "goods code %s is of level %d, below its Hier. Pos. %d", code, level, entry.HierPos)
+        }
+        codes[i] = code
+        byLanguage[entry.Language] = append(byLanguage[entry.Language], i)
+    }
+
+    for _, indexes := range byLanguage {
+        // Codes are checked in the order of the nomenclature, where the parent of a code is the closest
+        // code before it with one indent less
+        sort.SliceStable(indexes, func(a, b int) bool {
+            if codes[indexes[a]] != codes[indexes[b]] {
+                return codes[indexes[a]] < codes[indexes[b]]
+            }
+            return entries[indexes[a]].StartDate.Before(entries[indexes[b]].StartDate)
+        })
+
+        chapters := make(map[string]bool)
+        for _, i := range indexes {
+            if entries[i].HierPos == 2 {
+                chapters[codes[i][:2]] = true
+            }
+        }
+
+        var parents []int // Last code seen at each indent
+        for _, i := range indexes {
+            entry, code := entries[i], codes[i]
+
+            // Chapters and headings have no indent, the codes below a heading start at indent 1
+            if entry.HierPos <= 4 {
+                if entry.Indent != 0 {
+                    problem(i, "goods code %s at Hier. Pos. %d has indent %d, expected no indent", code, entry.HierPos, entry.Indent)
+                }
+                if entry.HierPos == 4 && !chapters[code[:2]] {
+                    problem(i, "goods code %s has no parent, chapter %s is not in the file", code, code[:2])
+                }
+                parents = []int{i}
+                continue
+            }
+            if entry.Indent == 0 {
+                problem(i, "goods code %s at Hier. Pos. %d has no indent", code, entry.HierPos)
+                continue
+            }
+
+            if entry.Indent > len(parents) {
+                problem(i, "goods code %s has no parent, no code at indent %d is before it", code, entry.Indent-1)
+                continue
+            }
+            parent := parents[entry.Indent-1]
+            parentCode, parentHierPos := codes[parent], entries[parent].HierPos
+            if code[:parentHierPos] != parentCode[:parentHierPos] {
+                problem(i, "goods code %s has no parent, the code before it at indent %d is %s", code, entry.Indent-1, parentCode)
+            } else if entry.HierPos < parentHierPos {
+                problem(i, "goods code %s at Hier. Pos. %d is above its parent %s at Hier. Pos. %d", code, entry.HierPos, parentCode, parentHierPos)
+            }
+
+            parents = append(parents[:entry.Indent], i)
+        }
+    }
+
+    // Report the problems in the order of the rows, not of the languages
+    sort.SliceStable(problems, func(a, b int) bool {
+        return problems[a].Index < problems[b].Index
+    })
+
+    return problems
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// BulkSaveEntries saves a batch of nomenclature entries with COPY into a staging table, merged into
+// nomenclatures and nomenclature_descriptions by two set-based statements
+func (p *NomenclatureParser) BulkSaveEntries(ctx context.Context, db *sql.DB, entries []NomenclatureEntry) (int, error) {
+	return copyEntries(ctx, db, entries)
+}
+
+// copyEntries saves the entries like insertEntries, in a few round trips whatever the size of the batch.
+// When a batch has a goods code period or description more than once, the last one is kept, as with insertEntries.
+func copyEntries(ctx context.Context, db *sql.DB, entries []NomenclatureEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// The staging table lives until the end of the transaction
+	_, err = tx.Exec(`
+		CREATE TEMPORARY TABLE nomenclature_staging (
+			position INTEGER NOT NULL,
+			goods_code VARCHAR(13) NOT NULL,
+			start_date DATE NOT NULL,
+			end_date DATE,
+			hierarchy_path TEXT NOT NULL,
+			indent SMALLINT NOT NULL,
+			language CHAR(2) NOT NULL,
+			description TEXT NOT NULL,
+			descr_start_date DATE NOT NULL
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to create staging table: %v", err)
+	}
+
+	copyStmt, err := tx.Prepare(pq.CopyIn("nomenclature_staging",
+		"position", "goods_code", "start_date", "end_date", "hierarchy_path", "indent",
+		"language", "description", "descr_start_date"))
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare copy statement: %v", err)
+	}
+
+	for i, entry := range entries {
+		_, err = copyStmt.Exec(
+			i,
+			entry.GoodsCode,
+			entry.StartDate,
+			entry.EndDate,
+			entry.HierarchyPath,
+			entry.Indent,
+			entry.Language,
+			entry.Description,
+			entry.DescrStartDate,
+		)
+		if err != nil {
+			copyStmt.Close()
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to copy item %s: %v", entry.GoodsCode, err)
+		}
+	}
+
+	// Flush the rows buffered by the driver
+	if _, err := copyStmt.Exec(); err != nil {
+		copyStmt.Close()
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to copy entries: %v", err)
+	}
+	if err := copyStmt.Close(); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to copy entries: %v", err)
+	}
+
+	// A row can only be updated once by a statement, so the last entry of each period is merged
+	_, err = tx.Exec(`
+		INSERT INTO nomenclatures (goods_code, start_date, end_date, hierarchy_path, indent)
+		SELECT DISTINCT ON (goods_code, start_date) goods_code, start_date, end_date, hierarchy_path::ltree, indent
+		FROM nomenclature_staging
+		ORDER BY goods_code, start_date, position DESC
+		ON CONFLICT (goods_code, start_date)
+		DO UPDATE SET end_date = EXCLUDED.end_date, hierarchy_path = EXCLUDED.hierarchy_path,
+			indent = EXCLUDED.indent, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to merge items: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO nomenclature_descriptions (nomenclature_id, language, description, descr_start_date)
+		SELECT DISTINCT ON (n.id, s.language, s.descr_start_date) n.id, s.language, s.description, s.descr_start_date
+		FROM nomenclature_staging s
+		JOIN nomenclatures n ON n.goods_code = s.goods_code AND n.start_date = s.start_date
+		ORDER BY n.id, s.language, s.descr_start_date, s.position DESC
+		ON CONFLICT (nomenclature_id, language, descr_start_date)
+		DO UPDATE SET description = EXCLUDED.description, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to merge descriptions: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
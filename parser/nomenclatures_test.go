@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNomenclatureValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	inverted := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	entries := []NomenclatureEntry{
+		{GoodsCode: "0100000000 80", HierPos: 2, Language: "EN", StartDate: start},
+		{GoodsCode: "0101000000 80", HierPos: 4, Language: "EN", StartDate: start},
+		{GoodsCode: "0101210000 80", HierPos: 6, Indent: 1, Language: "EN", StartDate: start},
+		{GoodsCode: "0101291000 80", HierPos: 8, Indent: 2, Language: "EN", StartDate: start},
+		// Hier. Pos. 6 would leave out its 8th digit
+		{GoodsCode: "0101290010 80", HierPos: 6, Indent: 1, Language: "EN", StartDate: start},
+		// Chapter 02 is not in the file
+		{GoodsCode: "0201000000 80", HierPos: 4, Language: "EN", StartDate: start},
+		{GoodsCode: "0201100000 80", HierPos: 6, Indent: 3, Language: "EN", StartDate: start},
+		{GoodsCode: "0102000000 80", HierPos: 4, Indent: 1, Language: "EN", StartDate: start, EndDate: &inverted},
+		// Every language is checked on its own
+		{GoodsCode: "0101210000 80", HierPos: 6, Indent: 1, Language: "LT", StartDate: start},
+		// Heading 0103 is not in the file, the code before it is heading 0102
+		{GoodsCode: "0103100000 80", HierPos: 6, Indent: 1, Language: "EN", StartDate: start},
+	}
+
+	parser := &NomenclatureParser{}
+	problems := parser.Validate(entries)
+
+	var indexes []int
+	for _, problem := range problems {
+		indexes = append(indexes, problem.Index)
+	}
+	expected := []int{4, 5, 6, 7, 7, 8, 9}
+	if !reflect.DeepEqual(indexes, expected) {
+		t.Fatalf("Validate() found problems at %v, expected %v: %v", indexes, expected, problems)
+	}
+}
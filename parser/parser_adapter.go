@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// AnyParser is a Parser of any entry type, as selected by name with createParser. The pipeline passes
+// entries on as interface{}, holding values of the entry type of the parser.
+type AnyParser interface {
+	ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error)
+	MapRow(RowData) (interface{}, error)
+	ProcessEntry(*interface{}) error
+	SaveEntries(ctx context.Context, db *sql.DB, entries []interface{}) (int, error)
+}
+
+// anyBulkSaver is an AnyParser adapting a BulkSaver
+type anyBulkSaver interface {
+	BulkSaveEntries(ctx context.Context, db *sql.DB, entries []interface{}) (int, error)
+}
+
+// anyValidator is an AnyParser adapting a Validator
+type anyValidator interface {
+	Validate(entries []interface{}) []ValidationProblem
+}
+
+// adaptParser adapts a Parser of entries of type T to AnyParser. The adapter is an anyBulkSaver or an
+// anyValidator if the parser is a BulkSaver or a Validator of T, and declares the columns, sheets and files of the
+// parser and whether it saves sequentially, or none when it does not.
+func adaptParser[T any](parser Parser[T]) AnyParser {
+	adapter := &parserAdapter[T]{parser: parser}
+	bulkSaver, isBulkSaver := parser.(BulkSaver[T])
+	validator, isValidator := parser.(Validator[T])
+
+	switch {
+	case isBulkSaver && isValidator:
+		return struct {
+			*parserAdapter[T]
+			bulkSaverAdapter[T]
+			validatorAdapter[T]
+		}{adapter, bulkSaverAdapter[T]{bulkSaver}, validatorAdapter[T]{validator}}
+	case isBulkSaver:
+		return struct {
+			*parserAdapter[T]
+			bulkSaverAdapter[T]
+		}{adapter, bulkSaverAdapter[T]{bulkSaver}}
+	case isValidator:
+		return struct {
+			*parserAdapter[T]
+			validatorAdapter[T]
+		}{adapter, validatorAdapter[T]{validator}}
+	default:
+		return adapter
+	}
+}
+
+// parserAdapter adapts a Parser of entries of type T to AnyParser
+type parserAdapter[T any] struct {
+	parser Parser[T]
+}
+
+func (a *parserAdapter[T]) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	return a.parser.ReadRows(ctx, config)
+}
+
+func (a *parserAdapter[T]) MapRow(row RowData) (interface{}, error) {
+	entry, err := a.parser.MapRow(row)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (a *parserAdapter[T]) ProcessEntry(entry *interface{}) error {
+	typed, ok := (*entry).(T)
+	if !ok {
+		return fmt.Errorf("unexpected entry type: %T", *entry)
+	}
+	if err := a.parser.ProcessEntry(&typed); err != nil {
+		return err
+	}
+
+	*entry = typed
+	return nil
+}
+
+func (a *parserAdapter[T]) SaveEntries(ctx context.Context, db *sql.DB, entries []interface{}) (int, error) {
+	typed, err := entriesOf[T](entries)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.parser.SaveEntries(ctx, db, typed)
+}
+
+// Columns returns the columns of the parser, none if it reads cells by position
+func (a *parserAdapter[T]) Columns() []Column {
+	if columnParser, ok := a.parser.(ColumnParser); ok {
+		return columnParser.Columns()
+	}
+	return nil
+}
+
+// Sheets returns the sheets of the parser, none if it reads the first sheet
+func (a *parserAdapter[T]) Sheets() []string {
+	if sheetParser, ok := a.parser.(SheetParser); ok {
+		return sheetParser.Sheets()
+	}
+	return nil
+}
+
+// MatchFiles returns the matcher of the files read by the parser, every file of a supported format if it
+// is not a FileMatcher
+func (a *parserAdapter[T]) MatchFiles(config ParserConfig) (func(path string) bool, error) {
+	if fileMatcher, ok := a.parser.(FileMatcher); ok {
+		return fileMatcher.MatchFiles(config)
+	}
+	return isInputFile, nil
+}
+
+// SavesSequentially reports whether the batches of the parser build on the batches before them
+func (a *parserAdapter[T]) SavesSequentially() bool {
+	sequentialSaver, ok := a.parser.(SequentialSaver)
+	return ok && sequentialSaver.SavesSequentially()
+}
+
+// bulkSaverAdapter adapts a BulkSaver of entries of type T to anyBulkSaver
+type bulkSaverAdapter[T any] struct {
+	bulkSaver BulkSaver[T]
+}
+
+func (a bulkSaverAdapter[T]) BulkSaveEntries(ctx context.Context, db *sql.DB, entries []interface{}) (int, error) {
+	typed, err := entriesOf[T](entries)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.bulkSaver.BulkSaveEntries(ctx, db, typed)
+}
+
+// validatorAdapter adapts a Validator of entries of type T to anyValidator
+type validatorAdapter[T any] struct {
+	validator Validator[T]
+}
+
+func (a validatorAdapter[T]) Validate(entries []interface{}) []ValidationProblem {
+	typed, err := entriesOf[T](entries)
+	if err != nil {
+		return []ValidationProblem{{Index: -1, Message: err.Error()}}
+	}
+
+	return a.validator.Validate(typed)
+}
+
+// entriesOf converts entries mapped by a Parser of T back to T
+func entriesOf[T any](entries []interface{}) ([]T, error) {
+	typed := make([]T, len(entries))
+	for i, e := range entries {
+		entry, ok := e.(T)
+		if !ok {
+			return nil, fmt.Errorf("invalid entry type at index %d: %T", i, e)
+		}
+		typed[i] = entry
+	}
+
+	return typed, nil
+}
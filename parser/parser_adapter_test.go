@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAdaptParser(t *testing.T) {
+	tests := []struct {
+		parserType        string
+		bulkSaver         bool
+		validator         bool
+		savesSequentially bool
+		declaresColumns   bool
+	}{
+		{parserType: "nomenclature", bulkSaver: true, validator: true, declaresColumns: true},
+		{parserType: "declarable_codes", validator: true, declaresColumns: true},
+		{parserType: "footnotes", savesSequentially: true, declaresColumns: true},
+		{parserType: "taric_deltas", savesSequentially: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parserType, func(t *testing.T) {
+			parser, err := createParser(tt.parserType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := parser.(anyBulkSaver); ok != tt.bulkSaver {
+				t.Errorf("adapter is an anyBulkSaver: %v, expected %v", ok, tt.bulkSaver)
+			}
+			if _, ok := parser.(anyValidator); ok != tt.validator {
+				t.Errorf("adapter is an anyValidator: %v, expected %v", ok, tt.validator)
+			}
+			if saves := parser.(SequentialSaver).SavesSequentially(); saves != tt.savesSequentially {
+				t.Errorf("SavesSequentially() = %v, expected %v", saves, tt.savesSequentially)
+			}
+			if columns := parser.(ColumnParser).Columns(); (len(columns) > 0) != tt.declaresColumns {
+				t.Errorf("Columns() = %v, expected columns: %v", columns, tt.declaresColumns)
+			}
+		})
+	}
+}
+
+func TestAdaptParserEntries(t *testing.T) {
+	parser := adaptParser[int](&pipelineTestParser{})
+
+	entry, err := parser.MapRow(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.ProcessEntry(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.SaveEntries(context.Background(), nil, []interface{}{entry, 13}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries of another parser are rejected instead of panicking
+	wrong := interface{}("12")
+	if err := parser.ProcessEntry(&wrong); err == nil {
+		t.Error("ProcessEntry() accepted an entry of another type")
+	}
+	if _, err := parser.SaveEntries(context.Background(), nil, []interface{}{12, "13"}); err == nil || err.Error() != "invalid entry type at index 1: string" {
+		t.Errorf("SaveEntries() error = %v, expected the entry at index 1 to be invalid", err)
+	}
+
+	if saved := parser.(*parserAdapter[int]).parser.(*pipelineTestParser).saved; !reflect.DeepEqual(saved, []int{12, 13}) {
+		t.Errorf("saved = %v, expected [12 13]", saved)
+	}
+}
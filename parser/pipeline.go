@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// mapWindowPerWorker is the number of rows each worker may be ahead of the first row not yet passed on.
+// A slow row holds back reading instead of rows piling up behind it.
+const mapWindowPerWorker = 64
+
+// mappedRow is a row with the entry it was mapped and processed into, or the error which rejected it
+type mappedRow struct {
+	Row       RowData
+	RowNumber int // Position of the row in the import, for rows without a row number in their file
+	Entry     interface{}
+	Stage     string // Stage the row was rejected at, empty if it was not rejected
+	Err       error
+}
+
+// mapRows maps and processes the rows on the given number of workers and passes them on in the order they
+// were read, so the batches and error counts of an import do not depend on the number of workers.
+// The first skip rows, committed by the import run being resumed, are numbered but neither mapped nor passed on.
+func mapRows(parser AnyParser, rowsChan <-chan RowData, workers int, skip int) <-chan mappedRow {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		seq    int
+		mapped mappedRow
+	}
+	jobs := make(chan job)
+	results := make(chan job)
+	window := make(chan struct{}, workers*mapWindowPerWorker)
+	mappedChan := make(chan mappedRow)
+
+	// Number the rows in the order they are read
+	go func() {
+		defer close(jobs)
+		seq := 0
+		for row := range rowsChan {
+			seq++
+			if seq <= skip {
+				continue
+			}
+			window <- struct{}{}
+			jobs <- job{seq: seq, mapped: mappedRow{Row: row, RowNumber: seq}}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.mapped = mapRow(parser, j.mapped)
+				results <- j
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Pass the rows on in order, holding back those mapped before the rows read ahead of them
+	go func() {
+		defer close(mappedChan)
+		pending := make(map[int]mappedRow)
+		next := skip + 1
+		for j := range results {
+			pending[j.seq] = j.mapped
+			for {
+				mapped, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				mappedChan <- mapped
+				<-window
+				next++
+			}
+		}
+	}()
+
+	return mappedChan
+}
+
+// mapRow maps and processes a single row, rejecting rows which could not be read
+func mapRow(parser AnyParser, mapped mappedRow) mappedRow {
+	if excelRow, ok := mapped.Row.(ExcelRow); ok && excelRow.ReadErr != nil {
+		mapped.Stage, mapped.Err = stageRead, excelRow.ReadErr
+		return mapped
+	}
+
+	entry, err := parser.MapRow(mapped.Row)
+	if err != nil {
+		mapped.Stage, mapped.Err = stageMap, err
+		return mapped
+	}
+
+	// Process the entry (e.g., calculate derived fields)
+	if err := parser.ProcessEntry(&entry); err != nil {
+		mapped.Stage, mapped.Err = stageProcess, err
+		return mapped
+	}
+
+	mapped.Entry = entry
+	return mapped
+}
+
+// drainMappedRows reads the remaining rows so the reading and mapping goroutines can finish. Cancel the
+// context of the reader first, so only the rows already read are mapped instead of the rest of the input.
+func drainMappedRows(mappedChan <-chan mappedRow) {
+	for range mappedChan {
+	}
+}
+
+// saveBatch is a batch of entries with the rows they were mapped from
+type saveBatch struct {
+	seq        int // Position of the batch in the import
+	Entries    []interface{}
+	Rows       []RowData
+	RowNumbers []int
+}
+
+func newSaveBatch(size int) saveBatch {
+	return saveBatch{
+		Entries:    make([]interface{}, 0, size),
+		Rows:       make([]RowData, 0, size),
+		RowNumbers: make([]int, 0, size),
+	}
+}
+
+// add adds a mapped row to the batch
+func (b *saveBatch) add(mapped mappedRow) {
+	b.Entries = append(b.Entries, mapped.Entry)
+	b.Rows = append(b.Rows, mapped.Row)
+	b.RowNumbers = append(b.RowNumbers, mapped.RowNumber)
+}
+
+// batchSavers saves batches on a bounded number of goroutines, each batch in its own transaction.
+// Save blocks while every saver is busy, which holds back reading and mapping.
+type batchSavers struct {
+	db          *sql.DB
+	saveEntries func(db *sql.DB, entries []interface{}) (int, error)
+	reject      func(row RowData, rowNumber int, stage string, err error) error
+	batches     chan saveBatch
+	wg          sync.WaitGroup
+
+	mu        sync.Mutex
+	seq       int
+	inserted  int
+	failed    int   // Rows of the failed batches
+	failedSeq int   // Position of the first failed batch
+	err       error // Error of the first failed batch
+
+	// Batches are committed in any order, the checkpoint follows the batches committed with every batch before them
+	checkpoint   func(row RowData, rowNumber int) error
+	checkpointMu sync.Mutex
+	committed    map[int]saveBatch // Batches committed after a batch not yet committed, by position
+	committedSeq int               // Position of the last batch committed with every batch before it
+}
+
+// newBatchSavers starts the savers, saving batches with save, e.g. the SaveEntries of a parser.
+// The rows of a failed batch are passed to reject. The last row of each batch committed with every batch
+// before it is passed to checkpoint, if it is not nil.
+func newBatchSavers(db *sql.DB, save func(*sql.DB, []interface{}) (int, error), savers int, reject func(RowData, int, string, error) error, checkpoint func(RowData, int) error) *batchSavers {
+	if savers < 1 {
+		savers = 1
+	}
+
+	s := &batchSavers{
+		db:          db,
+		saveEntries: save,
+		reject:      reject,
+		batches:     make(chan saveBatch),
+		checkpoint:  checkpoint,
+		committed:   make(map[int]saveBatch),
+	}
+	for i := 0; i < savers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for batch := range s.batches {
+				s.save(batch)
+			}
+		}()
+	}
+
+	return s
+}
+
+// Save passes the batch to a saver. It returns false without saving the batch once a batch has failed.
+func (s *batchSavers) Save(batch saveBatch) bool {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.seq++
+	batch.seq = s.seq
+	s.mu.Unlock()
+
+	s.batches <- batch
+	return true
+}
+
+func (s *batchSavers) save(batch saveBatch) {
+	insertedCount, err := s.saveEntries(s.db, batch.Entries)
+	if err == nil {
+		s.mu.Lock()
+		s.inserted += insertedCount
+		s.mu.Unlock()
+
+		s.commit(batch)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failed += len(batch.Rows)
+	for i, row := range batch.Rows {
+		if rejectErr := s.reject(row, batch.RowNumbers[i], stageSave, err); rejectErr != nil {
+			err = fmt.Errorf("%v, and %v", err, rejectErr)
+			break
+		}
+	}
+	err = fmt.Errorf("failed to insert entries: %v", err)
+
+	// Batches saved concurrently may fail in any order, the first one in the import is reported
+	if s.err == nil || batch.seq < s.failedSeq {
+		s.err, s.failedSeq = err, batch.seq
+	}
+}
+
+// commit moves the checkpoint past the batches committed with every batch before them. A failed batch
+// holds the checkpoint back, even if batches after it are committed.
+func (s *batchSavers) commit(batch saveBatch) {
+	if s.checkpoint == nil {
+		return
+	}
+
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+
+	s.committed[batch.seq] = batch
+	var last *saveBatch
+	for {
+		next, ok := s.committed[s.committedSeq+1]
+		if !ok {
+			break
+		}
+		delete(s.committed, s.committedSeq+1)
+		s.committedSeq++
+		last = &next
+	}
+	if last == nil {
+		return
+	}
+
+	// A checkpoint not recorded only costs a resumed import saving a few batches again
+	end := len(last.Rows) - 1
+	if err := s.checkpoint(last.Rows[end], last.RowNumbers[end]); err != nil {
+		log.Print(err)
+	}
+}
+
+// Wait waits for the batches passed to Save and returns the number of entries inserted, the number of rows
+// of the failed batches and the error of the first failed batch, if any
+func (s *batchSavers) Wait() (int, int, error) {
+	close(s.batches)
+	s.wg.Wait()
+
+	return s.inserted, s.failed, s.err
+}
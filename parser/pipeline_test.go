@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipelineTestParser maps rows holding numbers, taking longer for lower numbers so workers finish out of order
+type pipelineTestParser struct {
+	failBatches map[int]bool // Batches failing to save, by their first entry
+
+	mu    sync.Mutex
+	saved []int
+}
+
+func (p *pipelineTestParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	return nil, nil
+}
+
+func (p *pipelineTestParser) MapRow(row RowData) (int, error) {
+	number := row.(int)
+	time.Sleep(time.Duration(100-number%100) * time.Microsecond)
+	if number%7 == 0 {
+		return 0, fmt.Errorf("row %d is a multiple of 7", number)
+	}
+	return number, nil
+}
+
+func (p *pipelineTestParser) ProcessEntry(entry *int) error {
+	if *entry%11 == 0 {
+		return fmt.Errorf("entry %d is a multiple of 11", *entry)
+	}
+	return nil
+}
+
+func (p *pipelineTestParser) SaveEntries(ctx context.Context, db *sql.DB, entries []int) (int, error) {
+	if p.failBatches[entries[0]] {
+		return 0, fmt.Errorf("batch starting at %d failed", entries[0])
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range entries {
+		p.saved = append(p.saved, entry)
+	}
+	return len(entries), nil
+}
+
+func TestMapRowsKeepsOrder(t *testing.T) {
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(strconv.Itoa(workers), func(t *testing.T) {
+			rowsChan := make(chan RowData)
+			go func() {
+				defer close(rowsChan)
+				for number := 1; number <= 500; number++ {
+					rowsChan <- number
+				}
+			}()
+
+			var entries []int
+			var rejected []string
+			for mapped := range mapRows(adaptParser[int](&pipelineTestParser{}), rowsChan, workers, 0) {
+				if mapped.Row.(int) != mapped.RowNumber {
+					t.Fatalf("row %v has row number %d", mapped.Row, mapped.RowNumber)
+				}
+				if mapped.Err != nil {
+					rejected = append(rejected, fmt.Sprintf("%d:%s", mapped.RowNumber, mapped.Stage))
+					continue
+				}
+				entries = append(entries, mapped.Entry.(int))
+			}
+
+			var expectedEntries []int
+			var expectedRejected []string
+			for number := 1; number <= 500; number++ {
+				switch {
+				case number%7 == 0:
+					expectedRejected = append(expectedRejected, fmt.Sprintf("%d:%s", number, stageMap))
+				case number%11 == 0:
+					expectedRejected = append(expectedRejected, fmt.Sprintf("%d:%s", number, stageProcess))
+				default:
+					expectedEntries = append(expectedEntries, number)
+				}
+			}
+			if !reflect.DeepEqual(entries, expectedEntries) {
+				t.Fatalf("entries = %v, expected %v", entries, expectedEntries)
+			}
+			if !reflect.DeepEqual(rejected, expectedRejected) {
+				t.Fatalf("rejected = %v, expected %v", rejected, expectedRejected)
+			}
+		})
+	}
+}
+
+func TestBatchSaversReportFirstFailedBatch(t *testing.T) {
+	parser := &pipelineTestParser{failBatches: map[int]bool{31: true, 51: true}}
+
+	var mu sync.Mutex
+	var rejected []int
+	reject := func(row RowData, rowNumber int, stage string, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		rejected = append(rejected, rowNumber)
+		return nil
+	}
+
+	var checkpoints []int
+	checkpoint := func(row RowData, rowNumber int) error {
+		checkpoints = append(checkpoints, rowNumber)
+		return nil
+	}
+
+	savers := newBatchSavers(nil, func(db *sql.DB, entries []interface{}) (int, error) {
+		return adaptParser[int](parser).SaveEntries(context.Background(), db, entries)
+	}, 4, reject, checkpoint)
+	for start := 1; start <= 91; start += 10 {
+		batch := newSaveBatch(10)
+		for number := start; number < start+10; number++ {
+			batch.add(mappedRow{Row: number, RowNumber: number, Entry: number})
+		}
+		if !savers.Save(batch) {
+			break
+		}
+	}
+
+	inserted, failed, err := savers.Wait()
+	if err == nil || err.Error() != "failed to insert entries: batch starting at 31 failed" {
+		t.Fatalf("Wait() error = %v, expected the error of the batch starting at 31", err)
+	}
+	if inserted != len(parser.saved) {
+		t.Fatalf("Wait() inserted %d entries, %d were saved", inserted, len(parser.saved))
+	}
+	if failed != len(rejected) {
+		t.Fatalf("Wait() counted %d failed rows, %d were rejected", failed, len(rejected))
+	}
+	if len(rejected)%10 != 0 || len(rejected) == 0 {
+		t.Fatalf("rejected rows %v, expected the rows of whole batches", rejected)
+	}
+
+	// Batches after the failed one may be committed, the checkpoint stays before it
+	if len(checkpoints) == 0 || checkpoints[len(checkpoints)-1] != 30 || !sort.IntsAreSorted(checkpoints) {
+		t.Fatalf("checkpoints = %v, expected them to end at row 30", checkpoints)
+	}
+}
+
+func TestMapRowsSkipsResumedRows(t *testing.T) {
+	rowsChan := make(chan RowData)
+	go func() {
+		defer close(rowsChan)
+		for number := 1; number <= 50; number++ {
+			rowsChan <- number
+		}
+	}()
+
+	var rowNumbers []int
+	for mapped := range mapRows(adaptParser[int](&pipelineTestParser{}), rowsChan, 4, 40) {
+		rowNumbers = append(rowNumbers, mapped.RowNumber)
+	}
+
+	expected := []int{41, 42, 43, 44, 45, 46, 47, 48, 49, 50}
+	if !reflect.DeepEqual(rowNumbers, expected) {
+		t.Fatalf("row numbers = %v, expected %v", rowNumbers, expected)
+	}
+}
+
+func TestReadFilesKeepsOrder(t *testing.T) {
+	files := []inputFile{{Path: "a"}, {Path: "b"}, {Path: "c"}, {Path: "d"}}
+
+	rowsChan := make(chan RowData)
+	go func() {
+		defer close(rowsChan)
+		readFiles(context.Background(), files, ParserConfig{Workers: 2}, rowsChan, func(file inputFile, fileChan chan<- RowData) {
+			// The first files are the slowest to read
+			time.Sleep(time.Duration('e'-file.Path[0]) * time.Millisecond)
+			for i := 1; i <= 3; i++ {
+				fileChan <- fmt.Sprintf("%s%d", file.Path, i)
+			}
+		})
+	}()
+
+	var rows []string
+	for row := range rowsChan {
+		rows = append(rows, row.(string))
+	}
+
+	expected := []string{"a1", "a2", "a3", "b1", "b2", "b3", "c1", "c2", "c3", "d1", "d2", "d3"}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("rows = %v, expected %v", rows, expected)
+	}
+}
+
+func TestReadFilesStopsWhenCancelled(t *testing.T) {
+	files := []inputFile{{Path: "a"}, {Path: "b"}, {Path: "c"}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rowsChan := make(chan RowData)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readFiles(ctx, files, ParserConfig{Workers: 2}, rowsChan, func(file inputFile, fileChan chan<- RowData) {
+			// Files without end, read until the import is cancelled
+			for i := 0; sendRow(ctx, fileChan, i); i++ {
+			}
+		})
+	}()
+
+	<-rowsChan
+	<-rowsChan
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("readFiles() did not return after the import was cancelled")
+	}
+}
+
+// pipelineStreamParser reads numbers until its context is cancelled, counting the rows it sent
+type pipelineStreamParser struct {
+	pipelineTestParser
+	read int
+}
+
+func (p *pipelineStreamParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	rowsChan := make(chan RowData)
+	go func() {
+		defer close(rowsChan)
+		for number := 1; number <= 100000; number++ {
+			if !sendRow(ctx, rowsChan, number) {
+				return
+			}
+			p.read++
+		}
+	}()
+	return rowsChan, nil
+}
+
+func TestReadAndProcessFileStopsReadingAfterFailedBatch(t *testing.T) {
+	parser := &pipelineStreamParser{pipelineTestParser: pipelineTestParser{failBatches: map[int]bool{1: true}}}
+	config := ParserConfig{ChunkSize: 10, Workers: 2, Savers: 1}
+
+	processed, _, errored, err := readAndProcessFile(context.Background(), context.Background(), nil, &importRun{},
+		adaptParser[int](parser), config, nil)
+	if err == nil {
+		t.Fatal("readAndProcessFile() returned no error, expected the error of the first batch")
+	}
+
+	// The rows already read are mapped, the rest of the input is not
+	if parser.read >= 10000 {
+		t.Errorf("readAndProcessFile() read %d rows after the first batch failed", parser.read)
+	}
+	// The first batch holds 1-12 without 7 and 11, which are rejected when mapped
+	if errored < 12 {
+		t.Errorf("readAndProcessFile() counted %d errors, expected the rows of the failed batch and rejected rows", errored)
+	}
+	if processed > parser.read {
+		t.Errorf("readAndProcessFile() processed %d rows, only %d were read", processed, parser.read)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// QuotaBalanceEntry represents a single row from a quota balances snapshot
+type QuotaBalanceEntry struct {
+	OrderNumber string    // Quota order number, normalized to 6 digits (e.g. "091104")
+	StartDate   time.Time // Start date of the quota definition period the balance belongs to
+	BalanceDate time.Time // Date of the snapshot
+	Balance     float64   // Volume still available on the balance date
+}
+
+// QuotaBalancesParser implements the Parser interface for quota balance snapshot files
+type QuotaBalancesParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the quota balances snapshot
+func (p *QuotaBalancesParser) Columns() []Column {
+	return []Column{
+		{Name: "Order No.", Aliases: []string{"Order number"}},
+		startDateColumn,
+		{Name: "Balance date"},
+		{Name: "Balance"},
+	}
+}
+
+// MapRow converts an ExcelRow of a quota balances snapshot to a QuotaBalanceEntry.
+func (p *QuotaBalancesParser) MapRow(rowData RowData) (QuotaBalanceEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return QuotaBalanceEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	orderNumber, err := normalizeOrderNumber(row.Value("Order No."))
+	if err != nil {
+		return QuotaBalanceEntry{}, err
+	}
+
+	entry := QuotaBalanceEntry{OrderNumber: orderNumber}
+
+	entry.StartDate, err = time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return QuotaBalanceEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+
+	entry.BalanceDate, err = time.Parse("02-01-2006", row.Value("Balance date"))
+	if err != nil {
+		return QuotaBalanceEntry{}, fmt.Errorf("invalid balance date format: %v", err)
+	}
+
+	balance, err := parseOptionalDecimal(row.Value("Balance"))
+	if err != nil || balance == nil {
+		return QuotaBalanceEntry{}, fmt.Errorf("invalid balance %q", row.Value("Balance"))
+	}
+	entry.Balance = *balance
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for quota balances
+func (p *QuotaBalancesParser) ProcessEntry(entry *QuotaBalanceEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of quota balance entries to the database
+func (p *QuotaBalancesParser) SaveEntries(ctx context.Context, db *sql.DB, entries []QuotaBalanceEntry) (int, error) {
+	return insertQuotaBalanceEntries(ctx, db, entries)
+}
+
+func insertQuotaBalanceEntries(ctx context.Context, db *sql.DB, entries []QuotaBalanceEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	balanceStmt, err := tx.Prepare(`
+		INSERT INTO quota_balances (quota_definition_id, balance_date, balance)
+		SELECT id, $3, $4 FROM quota_definitions
+		WHERE order_number = $1 AND start_date = $2
+		ON CONFLICT (quota_definition_id, balance_date)
+		DO UPDATE SET balance = $4, updated_at = NOW()
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare balance statement: %v", err)
+	}
+	defer balanceStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		result, err := balanceStmt.Exec(entry.OrderNumber, entry.StartDate, entry.BalanceDate, entry.Balance)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert balance of quota %s: %v", entry.OrderNumber, err)
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			log.Printf("no definition of quota %s found starting %s", entry.OrderNumber, entry.StartDate.Format("2006-01-02"))
+			continue
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuotaEntry represents a single row from the tariff quotas export.
+// A row describes one definition period of a quota order number for one origin.
+type QuotaEntry struct {
+	OrderNumber      string     // Quota order number, normalized to 6 digits (e.g. "091104")
+	GeographicalArea string     // Origin the quota is open to: a country or a group (e.g. "1011" ERGA OMNES)
+	StartDate        time.Time  // Start date of the quota definition period
+	EndDate          *time.Time // End date of the quota definition period (can be empty)
+	InitialVolume    float64    // Volume available within the definition period
+	Unit             string     // Unit of the volume (e.g. "kg", "p/st")
+	GoodsCodes       []string   // Goods codes the quota applies to, normalized to 10 digits + 2-digit suffix
+}
+
+// QuotasParser implements the Parser interface for tariff quota files
+type QuotasParser struct {
+	BaseFileParser // Embed the BaseFileParser to inherit ReadRows for Excel and CSV files
+}
+
+// Columns returns the columns of the quotas export
+func (p *QuotasParser) Columns() []Column {
+	return []Column{
+		{Name: "Order No.", Aliases: []string{"Order number"}},
+		{Name: "Origin code"},
+		startDateColumn,
+		endDateColumn,
+		{Name: "Initial volume", Aliases: []string{"Volume"}},
+		{Name: "Unit"},
+		{Name: "Goods codes"},
+	}
+}
+
+// MapRow converts an ExcelRow of the quotas export to a QuotaEntry.
+// Goods codes are listed in a single cell, separated by commas, semicolons or line breaks.
+func (p *QuotasParser) MapRow(rowData RowData) (QuotaEntry, error) {
+	row, ok := rowData.(ExcelRow)
+	if !ok {
+		return QuotaEntry{}, fmt.Errorf("expected ExcelRow, got %T", rowData)
+	}
+
+	orderNumber, err := normalizeOrderNumber(row.Value("Order No."))
+	if err != nil {
+		return QuotaEntry{}, err
+	}
+
+	entry := QuotaEntry{
+		OrderNumber:      orderNumber,
+		GeographicalArea: strings.TrimSpace(row.Value("Origin code")),
+		Unit:             strings.TrimSpace(row.Value("Unit")),
+	}
+
+	if entry.GeographicalArea == "" {
+		return QuotaEntry{}, fmt.Errorf("origin code is required")
+	}
+
+	startDate, err := time.Parse("02-01-2006", row.Value("Start date"))
+	if err != nil {
+		return QuotaEntry{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	entry.StartDate = startDate
+
+	entry.EndDate, err = parseOptionalDate(row.Value("End date"), "02-01-2006")
+	if err != nil {
+		return QuotaEntry{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+
+	initialVolume, err := parseOptionalDecimal(row.Value("Initial volume"))
+	if err != nil || initialVolume == nil {
+		return QuotaEntry{}, fmt.Errorf("invalid initial volume %q", row.Value("Initial volume"))
+	}
+	entry.InitialVolume = *initialVolume
+
+	goodsCodes := strings.FieldsFunc(row.Value("Goods codes"), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+	for _, code := range goodsCodes {
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+
+		goodsCode, err := normalizeGoodsCode(code)
+		if err != nil {
+			return QuotaEntry{}, err
+		}
+		entry.GoodsCodes = append(entry.GoodsCodes, goodsCode)
+	}
+
+	return entry, nil
+}
+
+// ProcessEntry performs no additional processing for quotas
+func (p *QuotasParser) ProcessEntry(entry *QuotaEntry) error {
+	return nil
+}
+
+// SaveEntries saves a batch of quota entries to the database
+func (p *QuotasParser) SaveEntries(ctx context.Context, db *sql.DB, entries []QuotaEntry) (int, error) {
+	return insertQuotaEntries(ctx, db, entries)
+}
+
+func insertQuotaEntries(ctx context.Context, db *sql.DB, entries []QuotaEntry) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	orderNumberStmt, err := tx.Prepare(`
+		INSERT INTO quota_order_numbers (order_number)
+		VALUES ($1)
+		ON CONFLICT (order_number) DO NOTHING
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare order number statement: %v", err)
+	}
+	defer orderNumberStmt.Close()
+
+	definitionStmt, err := tx.Prepare(`
+		INSERT INTO quota_definitions (order_number, start_date, end_date, initial_volume, unit)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_number, start_date)
+		DO UPDATE SET end_date = $3, initial_volume = $4, unit = $5, updated_at = NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare definition statement: %v", err)
+	}
+	defer definitionStmt.Close()
+
+	originStmt, err := tx.Prepare(`
+		INSERT INTO quota_definition_origins (quota_definition_id, geographical_area_code)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare origin statement: %v", err)
+	}
+	defer originStmt.Close()
+
+	goodsCodeStmt, err := tx.Prepare(`
+		INSERT INTO quota_definition_goods_codes (quota_definition_id, goods_code)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to prepare goods code statement: %v", err)
+	}
+	defer goodsCodeStmt.Close()
+
+	// Track successful inserts/updates
+	successCount := 0
+
+	for _, entry := range entries {
+		if _, err := orderNumberStmt.Exec(entry.OrderNumber); err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert order number %s: %v", entry.OrderNumber, err)
+		}
+
+		// Origins and goods codes belong to the definition period, a row adds to those of the other origins
+		var definitionID int
+		err := definitionStmt.QueryRow(entry.OrderNumber, entry.StartDate, entry.EndDate, entry.InitialVolume, entry.Unit).Scan(&definitionID)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert definition of quota %s: %v", entry.OrderNumber, err)
+		}
+
+		if _, err := originStmt.Exec(definitionID, entry.GeographicalArea); err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to insert origin %s of quota %s: %v", entry.GeographicalArea, entry.OrderNumber, err)
+		}
+
+		for _, goodsCode := range entry.GoodsCodes {
+			if _, err := goodsCodeStmt.Exec(definitionID, goodsCode); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to insert goods code %s of quota %s: %v", goodsCode, entry.OrderNumber, err)
+			}
+		}
+
+		successCount++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
@@ -0,0 +1,72 @@
+-- Table to store tariff quota order numbers (e.g. 091104, written as 09.1104)
+CREATE TABLE quota_order_numbers (
+    order_number VARCHAR(6) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table to store quota definitions: the volume available within a quota period
+CREATE TABLE quota_definitions (
+    id SERIAL PRIMARY KEY,
+    order_number VARCHAR(6) NOT NULL REFERENCES quota_order_numbers(order_number) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    initial_volume NUMERIC(20, 3) NOT NULL,
+    unit TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(order_number, start_date)
+);
+
+-- Geographical areas a quota is open to within a definition period, as they change between periods
+CREATE TABLE quota_definition_origins (
+    quota_definition_id INTEGER NOT NULL REFERENCES quota_definitions(id) ON DELETE CASCADE,
+    geographical_area_code VARCHAR(4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (quota_definition_id, geographical_area_code)
+);
+
+-- Goods codes a quota applies to within a definition period
+CREATE TABLE quota_definition_goods_codes (
+    quota_definition_id INTEGER NOT NULL REFERENCES quota_definitions(id) ON DELETE CASCADE,
+    goods_code VARCHAR(13) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (quota_definition_id, goods_code)
+);
+
+-- Periodic snapshots of the volume still available within a quota definition
+CREATE TABLE quota_balances (
+    id SERIAL PRIMARY KEY,
+    quota_definition_id INTEGER NOT NULL REFERENCES quota_definitions(id) ON DELETE CASCADE,
+    balance_date DATE NOT NULL,
+    balance NUMERIC(20, 3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(quota_definition_id, balance_date)
+);
+
+-- Indexes for common queries
+CREATE INDEX idx_quota_definitions_validity ON quota_definitions(start_date, end_date);
+CREATE INDEX idx_quota_definition_goods_codes_goods_code ON quota_definition_goods_codes(goods_code);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_quota_order_numbers_modtime
+BEFORE UPDATE ON quota_order_numbers
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_quota_definitions_modtime
+BEFORE UPDATE ON quota_definitions
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_quota_balances_modtime
+BEFORE UPDATE ON quota_balances
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stages of the import at which a row can be rejected
+const (
+	stageRead    = "read"    // The row could not be read from the file, e.g. a malformed CSV record
+	stageMap     = "map"     // MapRow failed
+	stageProcess = "process" // ProcessEntry failed
+	stageSave    = "save"    // SaveEntries failed for the batch holding the row
+)
+
+// RejectedRow is a row left out of the import, written to the rejects file so it can be fixed and imported again
+type RejectedRow struct {
+	Source  string   `json:"source"`            // File the row came from, e.g. "Nomenclature EN.xlsx"
+	Sheet   string   `json:"sheet,omitempty"`   // Sheet the row came from, empty for CSV files
+	Row     int      `json:"row"`               // Row number as shown in Excel, or line number in a CSV file
+	Headers []string `json:"headers,omitempty"` // Header row of the file, to import the cells again
+	Cells   []string `json:"cells"`             // Cells of the row as read from the file
+	Stage   string   `json:"stage"`             // stageRead, stageMap, stageProcess or stageSave
+	Error   string   `json:"error"`
+}
+
+// newRejectedRow describes a row rejected at the given stage
+func newRejectedRow(row RowData, rowNumber int, stage string, err error) RejectedRow {
+	rejected := RejectedRow{Stage: stage, Error: err.Error()}
+	rejected.Source, rejected.Sheet, rejected.Row = rowPosition(row, rowNumber)
+
+	switch row := row.(type) {
+	case ExcelRow:
+		rejected.Headers = row.Headers
+		rejected.Cells = row.Cells
+	case TaricTransaction:
+		rejected.Cells = []string{strconv.Itoa(row.EnvelopeID), strconv.Itoa(row.TransactionID)}
+	default:
+		rejected.Cells = []string{fmt.Sprintf("%v", row)}
+	}
+
+	return rejected
+}
+
+// RejectsFile writes rejected rows as JSON lines, or as CSV if the file has a .csv extension.
+// It is safe for concurrent use, as rows of failed batches are written by the savers.
+type RejectsFile struct {
+	Path  string
+	Count int // Number of rows written
+
+	mu        sync.Mutex
+	file      *os.File
+	csvWriter *csv.Writer
+	encoder   *json.Encoder
+}
+
+// createRejectsFile creates the rejects file, replacing an earlier one at the same path
+func createRejectsFile(path string) (*RejectsFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rejects file: %v", err)
+	}
+
+	rejects := &RejectsFile{Path: path, file: file}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		rejects.csvWriter = csv.NewWriter(file)
+		// The cells follow the fixed columns, so rows of files with different headers can share the file
+		if err := rejects.csvWriter.Write([]string{"source", "sheet", "row", "stage", "error", "cells"}); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write rejects file: %v", err)
+		}
+	} else {
+		rejects.encoder = json.NewEncoder(file)
+	}
+
+	return rejects, nil
+}
+
+// Write appends a rejected row to the file
+func (r *RejectsFile) Write(rejected RejectedRow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	if r.csvWriter != nil {
+		record := append([]string{rejected.Source, rejected.Sheet, strconv.Itoa(rejected.Row), rejected.Stage, rejected.Error}, rejected.Cells...)
+		err = r.csvWriter.Write(record)
+	} else {
+		err = r.encoder.Encode(rejected)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write rejects file: %v", err)
+	}
+
+	r.Count++
+	return nil
+}
+
+// Close flushes and closes the file
+func (r *RejectsFile) Close() error {
+	if r.csvWriter != nil {
+		r.csvWriter.Flush()
+		if err := r.csvWriter.Error(); err != nil {
+			r.file.Close()
+			return fmt.Errorf("failed to write rejects file: %v", err)
+		}
+	}
+
+	return r.file.Close()
+}
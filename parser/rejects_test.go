@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRejectsFile(t *testing.T) {
+	row := ExcelRow{
+		Cells:   []string{"0101 21 00 00", "Grynaveisliai arkliai"},
+		Headers: []string{"Goods code", "Description"},
+		Source:  "Nomenclature LT.xlsx",
+		Sheet:   "Data",
+		Row:     12,
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{
+			name: "rejects.jsonl",
+			expected: `{"source":"Nomenclature LT.xlsx","sheet":"Data","row":12,"headers":["Goods code","Description"],` +
+				`"cells":["0101 21 00 00","Grynaveisliai arkliai"],"stage":"map","error":"invalid goods code"}` + "\n",
+		},
+		{
+			name: "rejects.csv",
+			expected: "source,sheet,row,stage,error,cells\n" +
+				"Nomenclature LT.xlsx,Data,12,map,invalid goods code,0101 21 00 00,Grynaveisliai arkliai\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			rejects, err := createRejectsFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The position in the import is only used for rows without a row number
+			if err := rejects.Write(newRejectedRow(row, 3400, stageMap, errors.New("invalid goods code"))); err != nil {
+				t.Fatal(err)
+			}
+			if err := rejects.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.expected {
+				t.Fatalf("rejects file = %q, expected %q", content, tt.expected)
+			}
+			if rejects.Count != 1 {
+				t.Fatalf("Count = %d, expected 1", rejects.Count)
+			}
+		})
+	}
+}
@@ -10,7 +10,8 @@ CREATE TABLE nomenclatures (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 
-    UNIQUE(goods_code)
+    -- A goods code can be closed and reopened, so every validity period is kept as a separate row
+    UNIQUE(goods_code, start_date)
 );
 
 CREATE TABLE nomenclature_descriptions (
@@ -22,10 +23,12 @@ CREATE TABLE nomenclature_descriptions (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     
-    UNIQUE(nomenclature_id, language)
+    -- Descriptions change over time, every description period is kept
+    UNIQUE(nomenclature_id, language, descr_start_date)
 );
 
 CREATE INDEX idx_nomenclatures_code ON nomenclatures(goods_code);
+CREATE INDEX idx_nomenclatures_validity ON nomenclatures(start_date, end_date);
 CREATE INDEX idx_nomenclature_descriptions_language ON nomenclature_descriptions(language);
 
 
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// TaricDeltasParser implements the Parser interface for TARIC3 XML delta files.
+// Every transaction of the files is a row, applied in envelope and transaction order, and the last applied
+// transaction is recorded in taric_delta_state so files already applied are skipped when read again.
+type TaricDeltasParser struct {
+	ignoredTypes map[string]bool // Record types without a table, logged once
+}
+
+// ReadRows reads a delta file, or all delta files of a directory or zip archive ordered by their envelope
+func (p *TaricDeltasParser) ReadRows(ctx context.Context, config ParserConfig) (<-chan RowData, error) {
+	files, err := inputFiles(config, isXMLFile)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeIDs := make(map[inputFile]int, len(files))
+	for _, file := range files {
+		envelopeID, err := readTaricEnvelopeID(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Name(), err)
+		}
+		envelopeIDs[file] = envelopeID
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return envelopeIDs[files[i]] < envelopeIDs[files[j]]
+	})
+
+	rowsChan := make(chan RowData)
+
+	go func() {
+		defer close(rowsChan)
+
+		if len(files) == 0 {
+			log.Printf("No XML files found")
+			return
+		}
+
+		for i, file := range files {
+			if len(files) > 1 {
+				log.Printf("Processing delta file %d/%d: %s (envelope %d)", i+1, len(files), file.Name(), envelopeIDs[file])
+			}
+
+			// Later files build on this one, so they are not read after a failure
+			if err := readTaricTransactions(ctx, file, rowsChan); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Failed to read delta file %s, stopping before the following files: %v", file.Name(), err)
+				return
+			}
+		}
+	}()
+
+	return rowsChan, nil
+}
+
+// MatchFiles returns the matcher of the delta files read by ReadRows
+func (p *TaricDeltasParser) MatchFiles(config ParserConfig) (func(path string) bool, error) {
+	return isXMLFile, nil
+}
+
+// SavesSequentially makes transactions be applied in order, as skipping transactions already applied relies on it
+func (p *TaricDeltasParser) SavesSequentially() bool {
+	return true
+}
+
+// MapRow orders the records of a transaction by their sequence number.
+// Invalid records fail the import when the transaction is applied, as skipping it would break the sequence.
+func (p *TaricDeltasParser) MapRow(rowData RowData) (TaricTransaction, error) {
+	transaction, ok := rowData.(TaricTransaction)
+	if !ok {
+		return TaricTransaction{}, fmt.Errorf("expected TaricTransaction, got %T", rowData)
+	}
+
+	sort.SliceStable(transaction.Records, func(i, j int) bool {
+		return transaction.Records[i].Sequence < transaction.Records[j].Sequence
+	})
+
+	return transaction, nil
+}
+
+// ProcessEntry performs no additional processing for delta transactions
+func (p *TaricDeltasParser) ProcessEntry(entry *TaricTransaction) error {
+	return nil
+}
+
+// SaveEntries applies a batch of transactions in a single database transaction, together with the
+// state of the last applied one. Transactions applied by an earlier import are skipped.
+func (p *TaricDeltasParser) SaveEntries(ctx context.Context, db *sql.DB, transactions []TaricTransaction) (int, error) {
+	return p.applyTransactions(ctx, db, transactions)
+}
+
+func (p *TaricDeltasParser) applyTransactions(ctx context.Context, db *sql.DB, transactions []TaricTransaction) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var lastEnvelopeID, lastTransactionID int
+	err = tx.QueryRow(`SELECT envelope_id, transaction_id FROM taric_delta_state FOR UPDATE`).Scan(&lastEnvelopeID, &lastTransactionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to read the last applied transaction: %v", err)
+	}
+
+	statements := newTaricStatements(tx)
+	defer statements.Close()
+
+	// Track applied transactions
+	successCount := 0
+	var last *TaricTransaction
+
+	for i, transaction := range transactions {
+		if taricTransactionApplied(transaction, lastEnvelopeID, lastTransactionID) {
+			continue
+		}
+
+		for _, record := range transaction.Records {
+			if err := p.applyRecord(statements, record); err != nil {
+				tx.Rollback()
+				return successCount, fmt.Errorf("failed to apply %s, envelope %d, transaction %d, record %d: %v",
+					transaction.Source, transaction.EnvelopeID, transaction.TransactionID, record.Sequence, err)
+			}
+		}
+
+		lastEnvelopeID, lastTransactionID = transaction.EnvelopeID, transaction.TransactionID
+		last = &transactions[i]
+		successCount++
+	}
+
+	if last != nil {
+		_, err = tx.Exec(`
+			INSERT INTO taric_delta_state (id, envelope_id, transaction_id, source)
+			VALUES (TRUE, $1, $2, $3)
+			ON CONFLICT (id)
+			DO UPDATE SET envelope_id = $1, transaction_id = $2, source = $3, updated_at = NOW()
+		`, last.EnvelopeID, last.TransactionID, last.Source)
+		if err != nil {
+			tx.Rollback()
+			return successCount, fmt.Errorf("failed to record the last applied transaction: %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return successCount, nil
+}
+
+// taricTransactionApplied reports whether a transaction is at or before the last applied one. Transaction
+// numbers restart in every envelope, so they are only compared within the same envelope.
+func taricTransactionApplied(transaction TaricTransaction, lastEnvelopeID, lastTransactionID int) bool {
+	if transaction.EnvelopeID != lastEnvelopeID {
+		return transaction.EnvelopeID < lastEnvelopeID
+	}
+
+	return transaction.TransactionID <= lastTransactionID
+}
+
+// applyRecord applies a single record to the table of its type
+func (p *TaricDeltasParser) applyRecord(statements *taricStatements, record TaricRecord) error {
+	if record.UpdateType != taricUpdate && record.UpdateType != taricDelete && record.UpdateType != taricInsert {
+		return fmt.Errorf("invalid update type %q of %s record", record.UpdateType, record.Type)
+	}
+
+	switch record.Type {
+	case "goods.nomenclature":
+		return applyGoodsNomenclature(statements, record)
+	case "goods.nomenclature.indents":
+		return applyGoodsNomenclatureIndent(statements, record)
+	case "goods.nomenclature.description.period":
+		return applyGoodsNomenclatureDescriptionPeriod(statements, record)
+	case "goods.nomenclature.description":
+		return applyGoodsNomenclatureDescription(statements, record)
+	case "measure":
+		return applyMeasure(statements, record)
+	case "measure.component":
+		return applyMeasureComponent(statements, record)
+	case "measure.excluded.geographical.area":
+		return applyMeasureExcludedArea(statements, record)
+	case "measure.condition":
+		// Conditional duties are only stored in the duty expression of the measures export, so every change
+		// is logged to show which measures are out of date
+		log.Printf("Warning: measure.condition record of measure SID %s is not applied, the duty expression "+
+			"of the measure is out of date until the measures export is imported again", record.Value("measure.sid"))
+		return nil
+	case "measure.condition.component":
+		log.Printf("Warning: measure.condition.component record of measure condition SID %s is not applied, the duty "+
+			"expression of its measure is out of date until the measures export is imported again",
+			record.Value("measure.condition.sid"))
+		return nil
+	case "footnote":
+		return applyFootnote(statements, record)
+	case "footnote.description":
+		return applyFootnoteDescription(statements, record)
+	case "footnote.association.measure":
+		return applyFootnoteMeasure(statements, record)
+	case "footnote.association.goods.nomenclature":
+		return applyFootnoteNomenclature(statements, record)
+	default:
+		if p.ignoredTypes == nil {
+			p.ignoredTypes = make(map[string]bool)
+		}
+		if !p.ignoredTypes[record.Type] {
+			log.Printf("Ignoring %s records, they are not stored", record.Type)
+			p.ignoredTypes[record.Type] = true
+		}
+		return nil
+	}
+}
+
+// taricStatements prepares the statements of a database transaction on first use, as a batch
+// usually has records of only a few types
+type taricStatements struct {
+	tx       *sql.Tx
+	prepared map[string]*sql.Stmt
+}
+
+func newTaricStatements(tx *sql.Tx) *taricStatements {
+	return &taricStatements{tx: tx, prepared: make(map[string]*sql.Stmt)}
+}
+
+// Exec executes the query with the given arguments, preparing it if it is the first use
+func (s *taricStatements) Exec(query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := s.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Exec(args...)
+}
+
+// Query runs the query with the given arguments, preparing it if it is the first use
+func (s *taricStatements) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := s.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Query(args...)
+}
+
+func (s *taricStatements) prepare(query string) (*sql.Stmt, error) {
+	stmt, ok := s.prepared[query]
+	if !ok {
+		var err error
+		stmt, err = s.tx.Prepare(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %v", err)
+		}
+		s.prepared[query] = stmt
+	}
+
+	return stmt, nil
+}
+
+// Close closes the prepared statements
+func (s *taricStatements) Close() {
+	for _, stmt := range s.prepared {
+		stmt.Close()
+	}
+}
@@ -0,0 +1,61 @@
+-- TARIC3 XML delta files reference rows by their TARIC SIDs, so the tables they update keep them.
+-- Rows imported from Excel exports have no SID until a delta file updates them by their natural key.
+ALTER TABLE nomenclatures ADD COLUMN IF NOT EXISTS sid INTEGER UNIQUE;
+ALTER TABLE measures ADD COLUMN IF NOT EXISTS sid INTEGER UNIQUE;
+ALTER TABLE nomenclature_descriptions ADD COLUMN IF NOT EXISTS description_period_sid INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_nomenclature_descriptions_period_sid ON nomenclature_descriptions(description_period_sid);
+
+-- Table to store description periods of goods codes, which give the start date of the descriptions
+-- sent in later records
+CREATE TABLE nomenclature_description_periods (
+    sid INTEGER PRIMARY KEY,
+    nomenclature_sid INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table to store the duty components of measures, from which the duty expression of a measure is rebuilt
+CREATE TABLE measure_components (
+    measure_sid INTEGER NOT NULL,
+    duty_expression_id VARCHAR(2) NOT NULL,       -- e.g. "01" % or amount, "04" + % or amount, "15" minimum
+    duty_amount TEXT NOT NULL DEFAULT '',         -- e.g. "12.800"
+    monetary_unit VARCHAR(3) NOT NULL DEFAULT '', -- e.g. "EUR", empty for a percentage
+    measurement_unit VARCHAR(3) NOT NULL DEFAULT '',
+    measurement_unit_qualifier VARCHAR(1) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (measure_sid, duty_expression_id)
+);
+
+-- Table to store the last applied transaction of the TARIC3 delta files, a single row
+CREATE TABLE taric_delta_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    envelope_id INTEGER NOT NULL,    -- Sequence number of the delta file, e.g. 230045
+    transaction_id INTEGER NOT NULL, -- Last applied transaction within the envelope
+    source TEXT NOT NULL DEFAULT '', -- File the transaction was read from
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_nomenclature_description_periods_modtime
+BEFORE UPDATE ON nomenclature_description_periods
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_measure_components_modtime
+BEFORE UPDATE ON measure_components
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+CREATE TRIGGER update_taric_delta_state_modtime
+BEFORE UPDATE ON taric_delta_state
+FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTaricTransactionApplied(t *testing.T) {
+	// The last applied transaction is transaction 7 of envelope 230045
+	tests := []struct {
+		name          string
+		envelopeID    int
+		transactionID int
+		expected      bool
+	}{
+		{"Last applied transaction", 230045, 7, true},
+		{"Earlier transaction of the envelope", 230045, 6, true},
+		{"Next transaction of the envelope", 230045, 8, false},
+		{"Older envelope with a higher transaction", 230044, 120, true},
+		{"Newer envelope with a lower transaction", 230046, 1, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			transaction := TaricTransaction{EnvelopeID: tc.envelopeID, TransactionID: tc.transactionID}
+			if applied := taricTransactionApplied(transaction, 230045, 7); applied != tc.expected {
+				t.Errorf("taricTransactionApplied(%d, %d) = %v, expected %v", tc.envelopeID, tc.transactionID, applied, tc.expected)
+			}
+		})
+	}
+}
+
+// taricTestDB connects to the test database of testDB, which also needs the tables of taric_deltas.sql, and
+// restores the last applied transaction when the test is done
+func taricTestDB(t *testing.T) *sql.DB {
+	db := testDB(t)
+
+	var envelopeID, transactionID int
+	var source string
+	err := db.QueryRow(`SELECT envelope_id, transaction_id, source FROM taric_delta_state`).Scan(&envelopeID, &transactionID, &source)
+	hasState := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal(err)
+	}
+
+	// Cleanups run last in, first out, so the state is restored before the database is closed
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM taric_delta_state`); err != nil {
+			t.Error(err)
+		}
+		if hasState {
+			_, err := db.Exec(`
+				INSERT INTO taric_delta_state (id, envelope_id, transaction_id, source) VALUES (TRUE, $1, $2, $3)
+			`, envelopeID, transactionID, source)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	return db
+}
+
+// taricGoodsCodeTransaction returns a transaction inserting, or deleting, a goods code of chapter 77 with the given SID
+func taricGoodsCodeTransaction(envelopeID, transactionID, sid int, updateType string) TaricTransaction {
+	return TaricTransaction{
+		EnvelopeID:    envelopeID,
+		TransactionID: transactionID,
+		Source:        fmt.Sprintf("DIFF_%d.xml", envelopeID),
+		Records: []TaricRecord{{
+			Type:       "goods.nomenclature",
+			UpdateType: updateType,
+			Sequence:   1,
+			Fields: map[string]string{
+				"goods.nomenclature.sid":     fmt.Sprint(sid),
+				"goods.nomenclature.item.id": fmt.Sprintf("77%02d000000", sid%100),
+				"producline.suffix":          "80",
+				"validity.start.date":        "2024-01-01",
+			},
+		}},
+	}
+}
+
+func TestApplyTaricTransactions(t *testing.T) {
+	db := taricTestDB(t)
+
+	_, err := db.Exec(`
+		INSERT INTO taric_delta_state (id, envelope_id, transaction_id, source) VALUES (TRUE, 990001, 5, 'DIFF_990001.xml')
+		ON CONFLICT (id) DO UPDATE SET envelope_id = 990001, transaction_id = 5, source = 'DIFF_990001.xml'
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactions := []TaricTransaction{
+		taricGoodsCodeTransaction(990000, 9, 977000001, taricInsert), // Older envelope
+		taricGoodsCodeTransaction(990001, 5, 977000002, taricInsert), // Last applied transaction
+		taricGoodsCodeTransaction(990001, 6, 977000003, taricInsert),
+		taricGoodsCodeTransaction(990001, 7, 977000004, taricInsert),
+		taricGoodsCodeTransaction(990002, 1, 977000004, taricDelete),
+	}
+
+	parser := &TaricDeltasParser{}
+	applied, err := parser.applyTransactions(context.Background(), db, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 3 {
+		t.Errorf("applyTransactions() applied %d transactions, expected 3", applied)
+	}
+
+	for sid, expected := range map[int]bool{977000001: false, 977000002: false, 977000003: true, 977000004: false} {
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM nomenclatures WHERE sid = $1)`, sid).Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		if exists != expected {
+			t.Errorf("goods code of SID %d exists = %v, expected %v", sid, exists, expected)
+		}
+	}
+
+	var envelopeID, transactionID int
+	var source string
+	if err := db.QueryRow(`SELECT envelope_id, transaction_id, source FROM taric_delta_state`).Scan(&envelopeID, &transactionID, &source); err != nil {
+		t.Fatal(err)
+	}
+	if envelopeID != 990002 || transactionID != 1 || source != "DIFF_990002.xml" {
+		t.Errorf("last applied transaction = %d/%d from %s, expected 990002/1 from DIFF_990002.xml", envelopeID, transactionID, source)
+	}
+
+	// Applying the same transactions again changes nothing
+	applied, err = parser.applyTransactions(context.Background(), db, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("applyTransactions() applied %d transactions again, expected 0", applied)
+	}
+}
+
+// taricFootnoteTransaction returns a transaction inserting, or deleting, a period of the test footnote ZZ902
+func taricFootnoteTransaction(transactionID int, updateType, startDate, endDate string) TaricTransaction {
+	return TaricTransaction{
+		EnvelopeID:    990010,
+		TransactionID: transactionID,
+		Source:        "DIFF_990010.xml",
+		Records: []TaricRecord{{
+			Type:       "footnote",
+			UpdateType: updateType,
+			Sequence:   1,
+			Fields: map[string]string{
+				"footnote.type.id":    "ZZ",
+				"footnote.id":         "902",
+				"validity.start.date": startDate,
+				"validity.end.date":   endDate,
+			},
+		}},
+	}
+}
+
+func TestApplyTaricFootnotePeriods(t *testing.T) {
+	db := taricTestDB(t)
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM footnotes WHERE footnote_type = 'ZZ'`); err != nil {
+			t.Error(err)
+		}
+	})
+
+	_, err := db.Exec(`
+		INSERT INTO taric_delta_state (id, envelope_id, transaction_id, source) VALUES (TRUE, 990009, 1, 'DIFF_990009.xml')
+		ON CONFLICT (id) DO UPDATE SET envelope_id = 990009, transaction_id = 1, source = 'DIFF_990009.xml'
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactions := []TaricTransaction{
+		taricFootnoteTransaction(1, taricInsert, "2010-01-01", ""),
+		taricFootnoteTransaction(2, taricInsert, "2015-01-01", ""),
+		taricFootnoteTransaction(3, taricUpdate, "2010-01-01", "2014-12-31"),
+		taricFootnoteTransaction(4, taricInsert, "2020-01-01", ""),
+		taricFootnoteTransaction(5, taricDelete, "2020-01-01", ""),
+	}
+
+	parser := &TaricDeltasParser{}
+	if _, err := parser.applyTransactions(context.Background(), db, transactions); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`
+		SELECT start_date::text, COALESCE(end_date::text, '') FROM footnotes
+		WHERE footnote_type = 'ZZ' AND code = '902' ORDER BY start_date
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var periods []string
+	for rows.Next() {
+		var startDate, endDate string
+		if err := rows.Scan(&startDate, &endDate); err != nil {
+			t.Fatal(err)
+		}
+		periods = append(periods, startDate+".."+endDate)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"2010-01-01..2014-12-31", "2015-01-01.."}
+	if strings.Join(periods, ",") != strings.Join(expected, ",") {
+		t.Errorf("footnote periods = %v, expected %v", periods, expected)
+	}
+}
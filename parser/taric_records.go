@@ -0,0 +1,579 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// applyGoodsNomenclature applies a goods code validity period. A period imported from an Excel export
+// is matched by its goods code and start date and takes the SID of the record.
+func applyGoodsNomenclature(statements *taricStatements, record TaricRecord) error {
+	sid, err := record.Int("goods.nomenclature.sid")
+	if err != nil {
+		return err
+	}
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`DELETE FROM nomenclatures WHERE sid = $1`, sid)
+		return err
+	}
+
+	goodsCode, err := normalizeGoodsCode(record.Value("goods.nomenclature.item.id") + record.Value("producline.suffix"))
+	if err != nil {
+		return err
+	}
+	hierarchyPath, err := getHierarchyPath(goodsCode, hierarchyLevel(goodsCode))
+	if err != nil {
+		return err
+	}
+	startDate, err := record.Date("validity.start.date")
+	if err != nil {
+		return err
+	}
+	endDate, err := record.OptionalDate("validity.end.date")
+	if err != nil {
+		return err
+	}
+
+	result, err := statements.Exec(`
+		UPDATE nomenclatures SET goods_code = $2, start_date = $3, end_date = $4, hierarchy_path = $5::ltree, updated_at = NOW()
+		WHERE sid = $1
+	`, sid, goodsCode, startDate, endDate, hierarchyPath)
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return nil
+	}
+
+	// The indent is set by the indent record sent with a new goods code
+	_, err = statements.Exec(`
+		INSERT INTO nomenclatures (sid, goods_code, start_date, end_date, hierarchy_path, indent)
+		VALUES ($1, $2, $3, $4, $5::ltree, 0)
+		ON CONFLICT (goods_code, start_date)
+		DO UPDATE SET sid = $1, end_date = $4, hierarchy_path = $5::ltree, updated_at = NOW()
+	`, sid, goodsCode, startDate, endDate, hierarchyPath)
+	return err
+}
+
+// applyGoodsNomenclatureIndent applies the indent of a goods code. Only the latest indent is kept, so a deleted
+// indent is left in place until the next one replaces it.
+func applyGoodsNomenclatureIndent(statements *taricStatements, record TaricRecord) error {
+	if record.UpdateType == taricDelete {
+		return nil
+	}
+
+	sid, err := record.Int("goods.nomenclature.sid")
+	if err != nil {
+		return err
+	}
+	indent, err := record.Int("number.indents")
+	if err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`UPDATE nomenclatures SET indent = $2, updated_at = NOW() WHERE sid = $1`, sid, indent)
+	return err
+}
+
+// applyGoodsNomenclatureDescriptionPeriod applies a description period, whose start date is the
+// descr_start_date of the descriptions sent for it
+func applyGoodsNomenclatureDescriptionPeriod(statements *taricStatements, record TaricRecord) error {
+	sid, err := record.Int("goods.nomenclature.description.period.sid")
+	if err != nil {
+		return err
+	}
+
+	if record.UpdateType == taricDelete {
+		if _, err := statements.Exec(`DELETE FROM nomenclature_descriptions WHERE description_period_sid = $1`, sid); err != nil {
+			return err
+		}
+		_, err = statements.Exec(`DELETE FROM nomenclature_description_periods WHERE sid = $1`, sid)
+		return err
+	}
+
+	nomenclatureSID, err := record.Int("goods.nomenclature.sid")
+	if err != nil {
+		return err
+	}
+	startDate, err := record.Date("validity.start.date")
+	if err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`
+		INSERT INTO nomenclature_description_periods (sid, nomenclature_sid, start_date)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (sid)
+		DO UPDATE SET nomenclature_sid = $2, start_date = $3, updated_at = NOW()
+	`, sid, nomenclatureSID, startDate)
+	if err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`
+		UPDATE nomenclature_descriptions SET descr_start_date = $2, updated_at = NOW()
+		WHERE description_period_sid = $1 AND descr_start_date <> $2
+	`, sid, startDate)
+	return err
+}
+
+// applyGoodsNomenclatureDescription applies the description of a goods code in one language
+func applyGoodsNomenclatureDescription(statements *taricStatements, record TaricRecord) error {
+	periodSID, err := record.Int("goods.nomenclature.description.period.sid")
+	if err != nil {
+		return err
+	}
+	language := record.Value("language.id")
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`
+			DELETE FROM nomenclature_descriptions WHERE description_period_sid = $1 AND language = $2
+		`, periodSID, language)
+		return err
+	}
+
+	result, err := statements.Exec(`
+		INSERT INTO nomenclature_descriptions (nomenclature_id, language, description, descr_start_date, description_period_sid)
+		SELECT n.id, $2::text, $3::text, p.start_date, p.sid
+		FROM nomenclature_description_periods p
+		JOIN nomenclatures n ON n.sid = p.nomenclature_sid
+		WHERE p.sid = $1
+		ON CONFLICT (nomenclature_id, language, descr_start_date)
+		DO UPDATE SET description = $3, description_period_sid = $1, updated_at = NOW()
+	`, periodSID, language, record.Value("description"))
+	if err != nil {
+		return err
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		log.Printf("no goods code found for description period SID %d of goods nomenclature SID %s",
+			periodSID, record.Value("goods.nomenclature.sid"))
+	}
+
+	return nil
+}
+
+// applyMeasure applies a measure on a goods code. A measure imported from an Excel export is matched by its
+// natural key and takes the SID of the record. Its duty expression is kept until its components are applied.
+func applyMeasure(statements *taricStatements, record TaricRecord) error {
+	sid, err := record.Int("measure.sid")
+	if err != nil {
+		return err
+	}
+
+	if record.UpdateType == taricDelete {
+		if _, err := statements.Exec(`DELETE FROM measure_components WHERE measure_sid = $1`, sid); err != nil {
+			return err
+		}
+		_, err = statements.Exec(`DELETE FROM measures WHERE sid = $1`, sid)
+		return err
+	}
+
+	// Measures without a goods code, e.g. on additional codes only, are not stored
+	if record.Value("goods.nomenclature.sid") == "" {
+		return nil
+	}
+	nomenclatureSID, err := record.Int("goods.nomenclature.sid")
+	if err != nil {
+		return err
+	}
+
+	measureType := record.Value("measure.type")
+	additionalCode := record.Value("additional.code.type") + record.Value("additional.code")
+	orderNumber := ""
+	if record.Value("ordernumber") != "" {
+		orderNumber, err = normalizeOrderNumber(record.Value("ordernumber"))
+		if err != nil {
+			return err
+		}
+	}
+	startDate, err := record.Date("validity.start.date")
+	if err != nil {
+		return err
+	}
+	endDate, err := record.OptionalDate("validity.end.date")
+	if err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`
+		INSERT INTO measure_types (code, description) VALUES ($1, '')
+		ON CONFLICT (code) DO NOTHING
+	`, measureType)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{
+		sid,
+		nomenclatureSID,
+		measureType,
+		record.Value("geographical.area"),
+		additionalCode,
+		orderNumber,
+		record.Value("measure.generating.regulation.id"),
+		startDate,
+		endDate,
+	}
+
+	result, err := statements.Exec(`
+		UPDATE measures m SET nomenclature_id = n.id, goods_code = n.goods_code, measure_type_code = $3,
+			geographical_area_code = $4, additional_code = $5, order_number = $6, regulation = $7,
+			start_date = $8, end_date = $9, updated_at = NOW()
+		FROM nomenclatures n
+		WHERE m.sid = $1 AND n.sid = $2
+	`, args...)
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return nil
+	}
+
+	result, err = statements.Exec(`
+		INSERT INTO measures
+		(sid, nomenclature_id, goods_code, measure_type_code, geographical_area_code, additional_code,
+		 order_number, regulation, start_date, end_date)
+		SELECT $1::integer, n.id, n.goods_code, $3::varchar, $4::varchar, $5::varchar, $6::varchar, $7::text, $8::date, $9::date
+		FROM nomenclatures n
+		WHERE n.sid = $2
+		ON CONFLICT (goods_code, measure_type_code, geographical_area_code, additional_code, order_number, start_date)
+		DO UPDATE SET sid = $1, nomenclature_id = EXCLUDED.nomenclature_id, regulation = $7, end_date = $9, updated_at = NOW()
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		log.Printf("no goods code found for goods nomenclature SID %d of measure SID %d", nomenclatureSID, sid)
+	}
+
+	return nil
+}
+
+// applyMeasureComponent applies a duty component of a measure and rebuilds the duty expression of the measure
+// from its components. The components of a measure imported from an Excel export are not known, so a component
+// updated or deleted before any other is applied leaves its duty expression out of date, which is logged.
+func applyMeasureComponent(statements *taricStatements, record TaricRecord) error {
+	sid, err := record.Int("measure.sid")
+	if err != nil {
+		return err
+	}
+	dutyExpressionID := record.Value("duty.expression.id")
+
+	rows, err := statements.Query(`SELECT 1 FROM measure_components WHERE measure_sid = $1 LIMIT 1`, sid)
+	if err != nil {
+		return err
+	}
+	known := rows.Next()
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`
+			DELETE FROM measure_components WHERE measure_sid = $1 AND duty_expression_id = $2
+		`, sid, dutyExpressionID)
+	} else {
+		_, err = statements.Exec(`
+			INSERT INTO measure_components
+			(measure_sid, duty_expression_id, duty_amount, monetary_unit, measurement_unit, measurement_unit_qualifier)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (measure_sid, duty_expression_id)
+			DO UPDATE SET duty_amount = $3, monetary_unit = $4, measurement_unit = $5, measurement_unit_qualifier = $6,
+				updated_at = NOW()
+		`, sid, dutyExpressionID, record.Value("duty.amount"), record.Value("monetary.unit.code"),
+			record.Value("measurement.unit.code"), record.Value("measurement.unit.qualifier.code"))
+	}
+	if err != nil {
+		return err
+	}
+
+	if !known && record.UpdateType != taricInsert {
+		log.Printf("Warning: components of measure SID %d are not known, its duty expression is out of date until "+
+			"the measures export is imported again", sid)
+		return nil
+	}
+
+	return rebuildMeasureDutyExpression(statements, sid)
+}
+
+// rebuildMeasureDutyExpression sets the duty expression of a measure to the one of its stored components
+func rebuildMeasureDutyExpression(statements *taricStatements, sid int) error {
+	rows, err := statements.Query(`
+		SELECT duty_expression_id, duty_amount, monetary_unit, measurement_unit, measurement_unit_qualifier
+		FROM measure_components WHERE measure_sid = $1
+		ORDER BY duty_expression_id
+	`, sid)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var components []taricDutyComponent
+	for rows.Next() {
+		var component taricDutyComponent
+		err := rows.Scan(&component.ExpressionID, &component.Amount, &component.MonetaryUnit,
+			&component.MeasurementUnit, &component.Qualifier)
+		if err != nil {
+			return err
+		}
+		components = append(components, component)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`
+		UPDATE measures SET duty_expression = $2, updated_at = NOW() WHERE sid = $1
+	`, sid, taricDutyExpression(components))
+	return err
+}
+
+// taricDutyComponent is a stored duty component of a measure, with the codes of the TARIC3 records
+type taricDutyComponent struct {
+	ExpressionID    string // Duty expression, e.g. "01" % or amount
+	Amount          string // e.g. "176.800"
+	MonetaryUnit    string // e.g. "EUR", empty for a percentage
+	MeasurementUnit string // e.g. "DTN"
+	Qualifier       string // Measurement unit qualifier, e.g. "E" net drained weight
+}
+
+// taricDutyExpressionFormats are the operators of the duty expressions, with the placeholder of the amount,
+// e.g. "MIN %s" for "MIN 10.00 EUR / 100 kg". Expressions without an amount are agricultural components.
+var taricDutyExpressionFormats = map[string]string{
+	"01": "%s",
+	"02": "- %s",
+	"04": "+ %s",
+	"12": "+ EA",
+	"14": "+ EA R",
+	"15": "MIN %s",
+	"17": "MAX %s",
+	"19": "+ %s",
+	"20": "+ %s",
+	"21": "+ ADSZ",
+	"25": "+ ADSZ R",
+	"27": "+ ADFM",
+	"29": "+ ADFM R",
+	"35": "MAX %s",
+	"36": "- %s",
+}
+
+// taricMeasurementUnits are the units of specific duties as written in the measures export
+var taricMeasurementUnits = map[string]string{
+	"DTN": "100 kg",
+	"KGM": "kg",
+	"TNE": "1000 kg",
+	"HLT": "hl",
+	"LTR": "l",
+	"NAR": "p/st",
+	"MIL": "1000 p/st",
+	"KLT": "1000 l",
+	"MTQ": "m3",
+	"MTK": "m2",
+}
+
+// taricDutyExpression writes the components of a measure as a duty expression of the measures export,
+// e.g. "12.800 % + 176.800 EUR / 100 kg". Components are expected in the order of their duty expression.
+func taricDutyExpression(components []taricDutyComponent) string {
+	var parts []string
+	for _, component := range components {
+		format, ok := taricDutyExpressionFormats[component.ExpressionID]
+		if !ok {
+			format = "+ %s"
+		}
+		if !strings.Contains(format, "%s") {
+			parts = append(parts, format)
+			continue
+		}
+
+		amount := component.Amount + " %"
+		if component.MonetaryUnit != "" {
+			amount = component.Amount + " " + component.MonetaryUnit
+			if component.MeasurementUnit != "" {
+				unit, ok := taricMeasurementUnits[component.MeasurementUnit]
+				if !ok {
+					unit = component.MeasurementUnit
+				}
+				amount += " / " + strings.TrimSpace(unit+" "+component.Qualifier)
+			}
+		}
+		parts = append(parts, fmt.Sprintf(format, amount))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// applyMeasureExcludedArea applies a geographical area excluded from a measure
+func applyMeasureExcludedArea(statements *taricStatements, record TaricRecord) error {
+	sid, err := record.Int("measure.sid")
+	if err != nil {
+		return err
+	}
+	area := record.Value("excluded.geographical.area")
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`
+			DELETE FROM measure_excluded_areas ex USING measures m
+			WHERE ex.measure_id = m.id AND m.sid = $1 AND ex.geographical_area_code = $2
+		`, sid, area)
+		return err
+	}
+
+	result, err := statements.Exec(`
+		INSERT INTO measure_excluded_areas (measure_id, geographical_area_code)
+		SELECT id, $2::varchar FROM measures WHERE sid = $1
+		ON CONFLICT DO NOTHING
+	`, sid, area)
+	if err != nil {
+		return err
+	}
+
+	// Measures without a goods code are not stored, nor are their exclusions
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		log.Printf("no measure found for excluded area %s of measure SID %d, or the area is already excluded", area, sid)
+	}
+
+	return nil
+}
+
+// applyFootnote applies a validity period of a footnote, identified by its type, code and start date.
+// A deleted record without a start date deletes every period of the footnote.
+func applyFootnote(statements *taricStatements, record TaricRecord) error {
+	footnoteType, code := record.Value("footnote.type.id"), record.Value("footnote.id")
+
+	if record.UpdateType == taricDelete {
+		if record.Value("validity.start.date") == "" {
+			_, err := statements.Exec(`DELETE FROM footnotes WHERE footnote_type = $1 AND code = $2`, footnoteType, code)
+			return err
+		}
+		startDate, err := record.Date("validity.start.date")
+		if err != nil {
+			return err
+		}
+		_, err = statements.Exec(`
+			DELETE FROM footnotes WHERE footnote_type = $1 AND code = $2 AND start_date = $3
+		`, footnoteType, code, startDate)
+		return err
+	}
+
+	startDate, err := record.Date("validity.start.date")
+	if err != nil {
+		return err
+	}
+	endDate, err := record.OptionalDate("validity.end.date")
+	if err != nil {
+		return err
+	}
+
+	_, err = statements.Exec(`
+		INSERT INTO footnotes (footnote_type, code, start_date, end_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (footnote_type, code, start_date)
+		DO UPDATE SET end_date = $4, updated_at = NOW()
+	`, footnoteType, code, startDate, endDate)
+	return err
+}
+
+// applyFootnoteDescription applies the description of a footnote in one language.
+// Only the latest description is kept, so description periods of footnotes are not stored.
+func applyFootnoteDescription(statements *taricStatements, record TaricRecord) error {
+	footnoteType, code, language := record.Value("footnote.type.id"), record.Value("footnote.id"), record.Value("language.id")
+
+	if record.UpdateType == taricDelete {
+		_, err := statements.Exec(`
+			DELETE FROM footnote_descriptions d USING footnotes f
+			WHERE d.footnote_id = f.id AND f.footnote_type = $1 AND f.code = $2 AND d.language = $3
+		`, footnoteType, code, language)
+		return err
+	}
+
+	result, err := statements.Exec(`
+		INSERT INTO footnote_descriptions (footnote_id, language, description)
+		SELECT id, $3::text, $4::text FROM footnotes WHERE footnote_type = $1 AND code = $2
+		ON CONFLICT (footnote_id, language)
+		DO UPDATE SET description = $4, updated_at = NOW()
+	`, footnoteType, code, language, record.Value("description"))
+	if err != nil {
+		return err
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		log.Printf("no footnote found for description of footnote %s%s", footnoteType, code)
+	}
+
+	return nil
+}
+
+// applyFootnoteMeasure applies the association of a footnote with a measure, using the latest period
+// of the footnote overlapping the measure
+func applyFootnoteMeasure(statements *taricStatements, record TaricRecord) error {
+	measureSID, err := record.Int("measure.sid")
+	if err != nil {
+		return err
+	}
+	footnoteType, code := record.Value("footnote.type.id"), record.Value("footnote.id")
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`
+			DELETE FROM footnote_measures fm USING footnotes f, measures m
+			WHERE fm.footnote_id = f.id AND fm.measure_id = m.id
+			AND f.footnote_type = $1 AND f.code = $2 AND m.sid = $3
+		`, footnoteType, code, measureSID)
+		return err
+	}
+
+	_, err = statements.Exec(`
+		INSERT INTO footnote_measures (footnote_id, measure_id)
+		SELECT DISTINCT ON (m.id) f.id, m.id FROM footnotes f, measures m
+		WHERE f.footnote_type = $1 AND f.code = $2 AND m.sid = $3
+		AND f.start_date <= COALESCE(m.end_date, 'infinity') AND (f.end_date IS NULL OR f.end_date >= m.start_date)
+		ORDER BY m.id, f.start_date DESC
+		ON CONFLICT DO NOTHING
+	`, footnoteType, code, measureSID)
+	return err
+}
+
+// applyFootnoteNomenclature applies the association of a footnote with a goods code, using the latest period
+// of the footnote overlapping the goods code
+func applyFootnoteNomenclature(statements *taricStatements, record TaricRecord) error {
+	nomenclatureSID, err := record.Int("goods.nomenclature.sid")
+	if err != nil {
+		return err
+	}
+	footnoteType, code := record.Value("footnote.type"), record.Value("footnote.id")
+
+	if record.UpdateType == taricDelete {
+		_, err = statements.Exec(`
+			DELETE FROM footnote_nomenclatures fn USING footnotes f, nomenclatures n
+			WHERE fn.footnote_id = f.id AND fn.nomenclature_id = n.id
+			AND f.footnote_type = $1 AND f.code = $2 AND n.sid = $3
+		`, footnoteType, code, nomenclatureSID)
+		return err
+	}
+
+	_, err = statements.Exec(`
+		INSERT INTO footnote_nomenclatures (footnote_id, nomenclature_id)
+		SELECT DISTINCT ON (n.id) f.id, n.id FROM footnotes f, nomenclatures n
+		WHERE f.footnote_type = $1 AND f.code = $2 AND n.sid = $3
+		AND f.start_date <= COALESCE(n.end_date, 'infinity') AND (f.end_date IS NULL OR f.end_date >= n.start_date)
+		ORDER BY n.id, f.start_date DESC
+		ON CONFLICT DO NOTHING
+	`, footnoteType, code, nomenclatureSID)
+	return err
+}
+
+// hierarchyLevel returns the level of a goods code, defined by its right-most pair of digits
+// which is different than 00, e.g. 4 for "0702000000 80" and 10 for "0702000007 80"
+func hierarchyLevel(goodsCode string) int {
+	for level := 10; level > 2; level -= 2 {
+		if len(goodsCode) >= level && goodsCode[level-2:level] != "00" {
+			return level
+		}
+	}
+
+	return 2
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTaricDutyExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []taricDutyComponent
+		expected   string
+	}{
+		{
+			name:       "Ad valorem",
+			components: []taricDutyComponent{{ExpressionID: "01", Amount: "12.800"}},
+			expected:   "12.800 %",
+		},
+		{
+			name: "Ad valorem and specific",
+			components: []taricDutyComponent{
+				{ExpressionID: "01", Amount: "12.800"},
+				{ExpressionID: "04", Amount: "176.800", MonetaryUnit: "EUR", MeasurementUnit: "DTN"},
+			},
+			expected: "12.800 % + 176.800 EUR / 100 kg",
+		},
+		{
+			name: "Limits and agricultural component",
+			components: []taricDutyComponent{
+				{ExpressionID: "01", Amount: "8.300"},
+				{ExpressionID: "12"},
+				{ExpressionID: "17", Amount: "12.800"},
+				{ExpressionID: "35", Amount: "24.200", MonetaryUnit: "EUR", MeasurementUnit: "DTN", Qualifier: "E"},
+			},
+			expected: "8.300 % + EA MAX 12.800 % MAX 24.200 EUR / 100 kg E",
+		},
+		{
+			name:       "Unknown unit",
+			components: []taricDutyComponent{{ExpressionID: "01", Amount: "2.000", MonetaryUnit: "EUR", MeasurementUnit: "GFI"}},
+			expected:   "2.000 EUR / GFI",
+		},
+		{
+			name:     "No components",
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := taricDutyExpression(tc.components); result != tc.expected {
+				t.Errorf("taricDutyExpression() = %q, expected %q", result, tc.expected)
+			}
+		})
+	}
+}
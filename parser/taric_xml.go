@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TARIC3 update types of a record
+const (
+	taricUpdate = "1"
+	taricDelete = "2"
+	taricInsert = "3"
+)
+
+// TaricTransaction is a transaction of a TARIC3 XML envelope, the unit in which delta files are applied
+type TaricTransaction struct {
+	EnvelopeID    int    // Sequence number of the delta file, e.g. 230045
+	TransactionID int    // Number of the transaction within the envelope
+	Source        string // File the transaction was read from
+	Records       []TaricRecord
+}
+
+// TaricRecord is a record of a TARIC3 transaction, creating, updating or deleting a single row
+type TaricRecord struct {
+	Type       string            // Element name of the record, e.g. "goods.nomenclature" or "measure"
+	UpdateType string            // taricUpdate, taricDelete or taricInsert
+	Sequence   int               // Sequence number of the record within the transaction
+	Fields     map[string]string // Values of the record by element name, e.g. "goods.nomenclature.item.id"
+}
+
+// Value returns the named field, or an empty string if the record does not have it
+func (r TaricRecord) Value(name string) string {
+	return strings.TrimSpace(r.Fields[name])
+}
+
+// Int returns the named numeric field, e.g. a SID
+func (r TaricRecord) Int(name string) (int, error) {
+	value, err := strconv.Atoi(r.Value(name))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q in %s record", name, r.Value(name), r.Type)
+	}
+
+	return value, nil
+}
+
+// Date returns the named date field
+func (r TaricRecord) Date(name string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", r.Value(name))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q in %s record", name, r.Value(name), r.Type)
+	}
+
+	return date, nil
+}
+
+// OptionalDate returns the named date field, or nil if it is empty
+func (r TaricRecord) OptionalDate(name string) (*time.Time, error) {
+	date, err := parseOptionalDate(r.Value(name), "2006-01-02")
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q in %s record", name, r.Value(name), r.Type)
+	}
+
+	return date, nil
+}
+
+// taricXMLRecord is the XML layout of a record, holding its data in a single element named after the record type
+type taricXMLRecord struct {
+	TransactionID  string          `xml:"transaction.id"`
+	RecordCode     string          `xml:"record.code"`
+	SubrecordCode  string          `xml:"subrecord.code"`
+	SequenceNumber int             `xml:"record.sequence.number"`
+	UpdateType     string          `xml:"update.type"`
+	Data           taricXMLElement `xml:",any"`
+}
+
+// taricXMLElement is the data element of a record, e.g. <oub:goods.nomenclature>
+type taricXMLElement struct {
+	XMLName xml.Name
+	Fields  []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// isXMLFile checks if the file has an XML extension
+func isXMLFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".xml")
+}
+
+// readTaricEnvelopeID reads the sequence number of the envelope of a delta file, without reading its transactions
+func readTaricEnvelopeID(file inputFile) (int, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("no envelope found")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read XML: %v", err)
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			if element.Name.Local != "envelope" {
+				return 0, fmt.Errorf("expected an envelope, found <%s>", element.Name.Local)
+			}
+			return taricIDAttr(element)
+		}
+	}
+}
+
+// readTaricTransactions streams the transactions of a delta file, sending each one to the channel once it is read.
+// It stops with the error of the context once the import is cancelled.
+func readTaricTransactions(ctx context.Context, file inputFile, rowsChan chan<- RowData) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	envelopeID := 0
+	var transaction *TaricTransaction
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read XML: %v", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "envelope":
+				envelopeID, err = taricIDAttr(element)
+				if err != nil {
+					return err
+				}
+
+			case "transaction":
+				id, err := taricIDAttr(element)
+				if err != nil {
+					return err
+				}
+				transaction = &TaricTransaction{EnvelopeID: envelopeID, TransactionID: id, Source: file.Name()}
+
+			case "record":
+				if transaction == nil {
+					return fmt.Errorf("record outside of a transaction")
+				}
+
+				var record taricXMLRecord
+				if err := decoder.DecodeElement(&record, &element); err != nil {
+					return fmt.Errorf("failed to read record of transaction %d: %v", transaction.TransactionID, err)
+				}
+				transaction.Records = append(transaction.Records, record.taricRecord())
+			}
+
+		case xml.EndElement:
+			if element.Name.Local == "transaction" && transaction != nil {
+				if !sendRow(ctx, rowsChan, *transaction) {
+					return ctx.Err()
+				}
+				transaction = nil
+			}
+		}
+	}
+}
+
+// taricRecord converts the XML layout of a record to a TaricRecord
+func (r taricXMLRecord) taricRecord() TaricRecord {
+	record := TaricRecord{
+		Type:       r.Data.XMLName.Local,
+		UpdateType: strings.TrimSpace(r.UpdateType),
+		Sequence:   r.SequenceNumber,
+		Fields:     make(map[string]string, len(r.Data.Fields)),
+	}
+	for _, field := range r.Data.Fields {
+		record.Fields[field.XMLName.Local] = field.Value
+	}
+
+	return record
+}
+
+// taricIDAttr returns the numeric id attribute of an envelope or transaction element
+func taricIDAttr(element xml.StartElement) (int, error) {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "id" {
+			id, err := strconv.Atoi(strings.TrimSpace(attr.Value))
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s id %q", element.Name.Local, attr.Value)
+			}
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s without an id", element.Name.Local)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const taricDeltaSample = `<?xml version="1.0" encoding="UTF-8"?>
+<env:envelope xmlns="urn:publicid:-:DGTAXUD:TARIC:MESSAGE:1.0" xmlns:env="urn:publicid:-:DGTAXUD:GENERAL:ENVELOPE:1.0" id="230045">
+  <env:transaction id="7">
+    <env:app.message id="1">
+      <oub:transmission xmlns:oub="urn:publicid:-:DGTAXUD:TARIC:MESSAGE:1.0">
+        <oub:record>
+          <oub:transaction.id>11</oub:transaction.id>
+          <oub:record.code>400</oub:record.code>
+          <oub:subrecord.code>05</oub:subrecord.code>
+          <oub:record.sequence.number>2</oub:record.sequence.number>
+          <oub:update.type>3</oub:update.type>
+          <oub:goods.nomenclature.indents>
+            <oub:goods.nomenclature.indent.sid>109876</oub:goods.nomenclature.indent.sid>
+            <oub:goods.nomenclature.sid>104567</oub:goods.nomenclature.sid>
+            <oub:number.indents>2</oub:number.indents>
+          </oub:goods.nomenclature.indents>
+        </oub:record>
+        <oub:record>
+          <oub:transaction.id>11</oub:transaction.id>
+          <oub:record.code>400</oub:record.code>
+          <oub:subrecord.code>00</oub:subrecord.code>
+          <oub:record.sequence.number>1</oub:record.sequence.number>
+          <oub:update.type>3</oub:update.type>
+          <oub:goods.nomenclature>
+            <oub:goods.nomenclature.sid>104567</oub:goods.nomenclature.sid>
+            <oub:goods.nomenclature.item.id>0702000007</oub:goods.nomenclature.item.id>
+            <oub:producline.suffix>80</oub:producline.suffix>
+            <oub:validity.start.date>2023-07-01</oub:validity.start.date>
+          </oub:goods.nomenclature>
+        </oub:record>
+      </oub:transmission>
+    </env:app.message>
+  </env:transaction>
+  <env:transaction id="8">
+    <env:app.message id="2">
+      <oub:transmission xmlns:oub="urn:publicid:-:DGTAXUD:TARIC:MESSAGE:1.0">
+        <oub:record>
+          <oub:transaction.id>12</oub:transaction.id>
+          <oub:record.code>430</oub:record.code>
+          <oub:subrecord.code>00</oub:subrecord.code>
+          <oub:record.sequence.number>1</oub:record.sequence.number>
+          <oub:update.type>2</oub:update.type>
+          <oub:measure>
+            <oub:measure.sid>3456789</oub:measure.sid>
+          </oub:measure>
+        </oub:record>
+      </oub:transmission>
+    </env:app.message>
+  </env:transaction>
+</env:envelope>
+`
+
+func TestReadTaricTransactions(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "DIT230045.xml")
+	if err := os.WriteFile(filePath, []byte(taricDeltaSample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := inputFile{Path: filePath}
+
+	envelopeID, err := readTaricEnvelopeID(file)
+	if err != nil || envelopeID != 230045 {
+		t.Fatalf("readTaricEnvelopeID() = %d, %v, expected 230045", envelopeID, err)
+	}
+
+	rowsChan := make(chan RowData)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- readTaricTransactions(context.Background(), file, rowsChan)
+		close(rowsChan)
+	}()
+
+	parser := &TaricDeltasParser{}
+	var transactions []TaricTransaction
+	for row := range rowsChan {
+		entry, err := parser.MapRow(row)
+		if err != nil {
+			t.Fatalf("MapRow() error: %v", err)
+		}
+		transactions = append(transactions, entry)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("readTaricTransactions() error: %v", err)
+	}
+
+	if len(transactions) != 2 {
+		t.Fatalf("readTaricTransactions() returned %d transactions, expected 2", len(transactions))
+	}
+
+	first := transactions[0]
+	if first.EnvelopeID != 230045 || first.TransactionID != 7 || first.Source != "DIT230045.xml" {
+		t.Errorf("first transaction = envelope %d, transaction %d, source %q", first.EnvelopeID, first.TransactionID, first.Source)
+	}
+
+	var types []string
+	for _, record := range first.Records {
+		types = append(types, record.Type)
+	}
+	if expected := []string{"goods.nomenclature", "goods.nomenclature.indents"}; !reflect.DeepEqual(types, expected) {
+		t.Errorf("records in sequence order = %q, expected %q", types, expected)
+	}
+
+	nomenclature := first.Records[0]
+	if nomenclature.UpdateType != taricInsert || nomenclature.Value("goods.nomenclature.item.id") != "0702000007" {
+		t.Errorf("goods.nomenclature record = %+v", nomenclature)
+	}
+	if endDate, err := nomenclature.OptionalDate("validity.end.date"); endDate != nil || err != nil {
+		t.Errorf("OptionalDate(%q) = %v, %v, expected no date", "validity.end.date", endDate, err)
+	}
+
+	measure := transactions[1].Records[0]
+	if measure.Type != "measure" || measure.UpdateType != taricDelete || measure.Value("measure.sid") != "3456789" {
+		t.Errorf("measure record = %+v", measure)
+	}
+}
+
+func TestHierarchyLevel(t *testing.T) {
+	tests := map[string]int{
+		"0700000000 80": 2,
+		"0702000000 80": 4,
+		"0702001000 80": 8,
+		"0702000007 80": 10,
+	}
+
+	for goodsCode, expected := range tests {
+		if level := hierarchyLevel(goodsCode); level != expected {
+			t.Errorf("hierarchyLevel(%q) = %d, expected %d", goodsCode, level, expected)
+		}
+	}
+}
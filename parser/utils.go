@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// normalizeGoodsCode converts a goods code from TARIC exports into the "0101210000 80" form
+// stored in nomenclatures.goods_code. Spaces are ignored and suffix "80" is assumed for
+// 10-digit codes, since measures and other TARIC data only refer to actual goods lines.
+func normalizeGoodsCode(goodsCode string) (string, error) {
+	var digits strings.Builder
+	for _, r := range goodsCode {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		} else if !unicode.IsSpace(r) {
+			return "", fmt.Errorf("invalid goods code %q", goodsCode)
+		}
+	}
+
+	code := digits.String()
+	switch len(code) {
+	case 10:
+		return code + " 80", nil
+	case 12:
+		return code[:10] + " " + code[10:], nil
+	default:
+		return "", fmt.Errorf("invalid goods code %q: expected 10 digits with an optional 2 digit suffix", goodsCode)
+	}
+}
+
+// normalizeOrderNumber converts a tariff quota order number from TARIC exports, e.g. "09.1104",
+// into the 6-digit form stored in measures.order_number. Dots and spaces are ignored.
+func normalizeOrderNumber(orderNumber string) (string, error) {
+	var digits strings.Builder
+	for _, r := range orderNumber {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		} else if r != '.' && !unicode.IsSpace(r) {
+			return "", fmt.Errorf("invalid order number %q", orderNumber)
+		}
+	}
+
+	if digits.Len() != 6 {
+		return "", fmt.Errorf("invalid order number %q: expected 6 digits", orderNumber)
+	}
+
+	return digits.String(), nil
+}
+
+// parseOptionalDecimal parses a decimal cell, returning nil for empty cells.
+// Exports in LT use a decimal comma, so both separators are accepted.
+func parseOptionalDecimal(value string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &number, nil
+}
+
+// parseOptionalDate parses a date cell using the given layout, returning nil for empty cells
+func parseOptionalDate(value string, layout string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &date, nil
+}
+
+// nomenclaturePeriod is a single validity period of a goods code stored in nomenclatures
+type nomenclaturePeriod struct {
+	ID        int
+	StartDate time.Time
+	EndDate   *time.Time
+}
+
+// findNomenclaturePeriods loads all validity periods of the given goods codes, keyed by goods code
+func findNomenclaturePeriods(tx *sql.Tx, goodsCodes []string) (map[string][]nomenclaturePeriod, error) {
+	rows, err := tx.Query(`
+		SELECT id, goods_code, start_date, end_date FROM nomenclatures
+		WHERE goods_code = ANY($1)
+		ORDER BY goods_code, start_date
+	`, pq.Array(goodsCodes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing nomenclatures: %v", err)
+	}
+	defer rows.Close()
+
+	periods := make(map[string][]nomenclaturePeriod)
+	for rows.Next() {
+		var period nomenclaturePeriod
+		var code string
+		var endDate sql.NullTime
+		if err := rows.Scan(&period.ID, &code, &period.StartDate, &endDate); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if endDate.Valid {
+			period.EndDate = &endDate.Time
+		}
+		periods[code] = append(periods[code], period)
+	}
+
+	return periods, rows.Err()
+}
+
+// nomenclatureIDOn returns the ID of the period valid on the given date.
+// If no period covers the date, the latest period started before it is used.
+func nomenclatureIDOn(periods []nomenclaturePeriod, date time.Time) (int, bool) {
+	found := false
+	id := 0
+	for _, period := range periods {
+		if period.StartDate.After(date) {
+			break
+		}
+		id = period.ID
+		found = true
+		if period.EndDate == nil || !period.EndDate.Before(date) {
+			return id, true
+		}
+	}
+
+	return id, found
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNomenclatureIDOn(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	closed := date("2019-12-31")
+	periods := []nomenclaturePeriod{
+		{ID: 1, StartDate: date("2010-01-01"), EndDate: &closed},
+		{ID: 2, StartDate: date("2021-01-01")},
+	}
+
+	tests := []struct {
+		date     string
+		expected int
+		found    bool
+	}{
+		{"2009-12-31", 0, false}, // Before the first period, the measure fails its batch
+		{"2015-06-01", 1, true},
+		{"2020-06-01", 1, true}, // Between periods, the latest period started before is used
+		{"2024-01-01", 2, true},
+	}
+
+	for _, tc := range tests {
+		id, found := nomenclatureIDOn(periods, date(tc.date))
+		if id != tc.expected || found != tc.found {
+			t.Errorf("nomenclatureIDOn(%s) = %d, %v, expected %d, %v", tc.date, id, found, tc.expected, tc.found)
+		}
+	}
+	if _, found := nomenclatureIDOn(nil, date("2024-01-01")); found {
+		t.Error("nomenclatureIDOn() found a period of a goods code without periods")
+	}
+}
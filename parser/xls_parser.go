@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shakinm/xlsReader/xls"
+	"github.com/shakinm/xlsReader/xls/record"
+	"github.com/shakinm/xlsReader/xls/structure"
+)
+
+// xlsRow is a non-empty row of a legacy Excel sheet
+type xlsRow struct {
+	Index int      // Row number in the sheet as shown in Excel
+	Cells []string // Cells by column, empty strings for missing cells
+}
+
+// xlsWorkbook is a legacy Excel 97-2003 (BIFF) workbook, which is read into memory as a whole
+type xlsWorkbook struct {
+	workbook xls.Workbook
+	Sheets   []string
+}
+
+// isXLSFile checks if the file has the legacy Excel extension
+func isXLSFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".xls")
+}
+
+// openXLS reads a legacy Excel file
+func openXLS(file inputFile) (wb *xlsWorkbook, err error) {
+	// The BIFF reader panics on some malformed files instead of returning an error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read legacy Excel file: %v", r)
+		}
+	}()
+
+	data, err := file.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	workbook, err := xls.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	wb = &xlsWorkbook{workbook: workbook}
+	for i := 0; i < workbook.GetNumberSheets(); i++ {
+		sheet, err := workbook.GetSheet(i)
+		if err != nil {
+			return nil, err
+		}
+		wb.Sheets = append(wb.Sheets, sheet.GetName())
+	}
+
+	return wb, nil
+}
+
+// Rows returns the non-empty rows of a sheet, the header row first
+func (wb *xlsWorkbook) Rows(sheet string) (rows []xlsRow, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read sheet %q: %v", sheet, r)
+		}
+	}()
+
+	for i, name := range wb.Sheets {
+		if name != sheet {
+			continue
+		}
+
+		s, err := wb.workbook.GetSheet(i)
+		if err != nil {
+			return nil, err
+		}
+
+		for index := 0; index < s.GetNumberRows(); index++ {
+			row, err := s.GetRow(index)
+			if err != nil {
+				return nil, err
+			}
+
+			// Rows without cells are returned with a single blank cell
+			cols := row.GetCols()
+			cells := make([]string, len(cols))
+			empty := true
+			for col, cell := range cols {
+				cells[col] = wb.cellValue(cell)
+				if cells[col] != "" {
+					empty = false
+				}
+			}
+			if empty {
+				continue
+			}
+			rows = append(rows, xlsRow{Index: index + 1, Cells: cells})
+		}
+
+		return rows, nil
+	}
+
+	return nil, fmt.Errorf("sheet %q not found", sheet)
+}
+
+// cellValue returns the value of a cell as read from .xlsx files: numbers unformatted
+// and dates as YYYY-MM-DD, or RFC3339 if they have a time
+func (wb *xlsWorkbook) cellValue(cell structure.CellData) string {
+	switch cell.(type) {
+	case *record.Number, *record.Rk:
+		if wb.isDateCell(cell) {
+			return excelDateString(cell.GetFloat64())
+		}
+	}
+
+	return cell.GetString()
+}
+
+// isDateCell checks if the number format of a numeric cell shows it as a date
+func (wb *xlsWorkbook) isDateCell(cell structure.CellData) bool {
+	xf := wb.workbook.GetXFbyIndex(cell.GetXFIndex())
+	formatIndex := xf.GetFormatIndex()
+
+	// Built-in date and time formats
+	if (formatIndex >= 14 && formatIndex <= 22) || (formatIndex >= 45 && formatIndex <= 47) {
+		return true
+	}
+	if formatIndex < 164 {
+		return false
+	}
+
+	format := wb.workbook.GetFormatByIndex(formatIndex)
+	return isDateFormat(format.String())
+}
+
+// isDateFormat checks if a custom number format (e.g. "dd.mm.yyyy") formats a date or time
+func isDateFormat(format string) bool {
+	inQuotes := false
+	inBrackets := false
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '\\':
+			i++ // Escaped literal character
+		case c == '[':
+			inBrackets = true // Color or locale, e.g. [Red] or [$-409]
+		case c == ']':
+			inBrackets = false
+		case inBrackets:
+		case strings.ContainsRune("dmyhsDMYHS", rune(c)):
+			return true
+		}
+	}
+
+	return false
+}
+
+// excelDateString converts an Excel serial date to YYYY-MM-DD, or RFC3339 if it has a time
+func excelDateString(serial float64) string {
+	excelEpoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+	days := int(serial)
+	date := excelEpoch.AddDate(0, 0, days).Add(time.Duration((serial - float64(days)) * float64(24*time.Hour)).Round(time.Second))
+
+	if date.Hour() == 0 && date.Minute() == 0 && date.Second() == 0 {
+		return date.Format("2006-01-02")
+	}
+
+	return date.Format(time.RFC3339)
+}
+
+// validateXLSFileHeaders returns the problems found in the selected sheets of a single legacy Excel file
+func validateXLSFileHeaders(file inputFile, config ParserConfig) []string {
+	wb, err := openXLS(file)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to open Excel file: %v", err)}
+	}
+
+	sheets, err := selectSheets(wb.Sheets, config.Sheets)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	for _, sheet := range sheets {
+		rows, err := wb.Rows(sheet)
+		if err == nil && len(rows) == 0 {
+			err = fmt.Errorf("no header row found")
+		}
+		if err == nil && len(config.Columns) > 0 {
+			_, err = mapColumns(trimHeaders(rows[0].Cells), config.Columns)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("sheet %q: %v", sheet, err))
+		}
+	}
+
+	return problems
+}
+
+// processXLSFile reads the selected sheets of a single legacy Excel file and sends rows to the channel without closing it
+func processXLSFile(ctx context.Context, file inputFile, config ParserConfig, rowsChan chan<- RowData) {
+	wb, err := openXLS(file)
+	if err != nil {
+		log.Printf("Failed to open Excel file %s: %v", file.Name(), err)
+		return
+	}
+
+	sheets, err := selectSheets(wb.Sheets, config.Sheets)
+	if err != nil {
+		log.Printf("Failed to select sheets of the Excel file %s: %v", file.Name(), err)
+		return
+	}
+
+	filename := file.Name()
+	for _, sheet := range sheets {
+		rows, err := wb.Rows(sheet)
+		if err != nil {
+			log.Printf("Failed to read %s, sheet %q: %v", filename, sheet, err)
+			continue
+		}
+
+		if !processXLSSheet(ctx, rows, filename, sheet, config.Columns, rowsChan) {
+			return
+		}
+	}
+}
+
+// processXLSSheet sends the rows of a legacy Excel sheet to the channel, the first row holding the headers.
+// It returns false if the import was cancelled before every row was sent.
+func processXLSSheet(ctx context.Context, rows []xlsRow, filename string, sheet string, columns []Column, rowsChan chan<- RowData) bool {
+	if len(rows) == 0 {
+		return true
+	}
+
+	headers := trimHeaders(rows[0].Cells)
+
+	var columnIndexes map[string]int
+	if len(columns) > 0 {
+		var err error
+		columnIndexes, err = mapColumns(headers, columns)
+		if err != nil {
+			log.Printf("Invalid headers in %s, sheet %q: %v", filename, sheet, err)
+			return true
+		}
+	}
+
+	for _, row := range rows[1:] {
+		cells := row.Cells
+		for len(cells) < len(headers) {
+			cells = append(cells, "")
+		}
+
+		excelRow := ExcelRow{
+			Cells:   cells,
+			Headers: headers,
+			Width:   len(headers),
+			Source:  filename,
+			Sheet:   sheet,
+			Row:     row.Index,
+			columns: columnIndexes,
+		}
+		if !sendRow(ctx, rowsChan, excelRow) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestIsDateFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected bool
+	}{
+		{"dd.mm.yyyy", true},
+		{"yyyy-mm-dd hh:mm", true},
+		{"[$-427]d mmmm yyyy", true},
+		{"General", false},
+		{"0.00", false},
+		{"#,##0.000", false},
+		{"[Red]0.00", false},
+		{`0.00" days"`, false},
+		{`0\d`, false},
+	}
+
+	for _, tc := range tests {
+		if result := isDateFormat(tc.format); result != tc.expected {
+			t.Errorf("isDateFormat(%q) = %v, expected %v", tc.format, result, tc.expected)
+		}
+	}
+}
+
+func TestExcelDateString(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		expected string
+	}{
+		{43531, "2019-03-07"},
+		{43531.55069444, "2019-03-07T13:13:00Z"},
+		{36526, "2000-01-01"},
+	}
+
+	for _, tc := range tests {
+		if result := excelDateString(tc.serial); result != tc.expected {
+			t.Errorf("excelDateString(%v) = %q, expected %q", tc.serial, result, tc.expected)
+		}
+	}
+}
+
+// xlsCell is a cell of a generated legacy Excel sheet, either a string or a number shown with the number format
+// of its XF: 0 General, 1 the custom format "dd.mm.yyyy" or 2 the built-in date format m/d/yy
+type xlsCell struct {
+	Row, Col int
+	Text     string
+	Number   float64
+	XF       int
+}
+
+// writeXLSFile writes a BIFF8 workbook with a single sheet in a compound file, as saved by Excel 97-2003
+func writeXLSFile(t *testing.T, path string, sheet string, cells []xlsCell) {
+	t.Helper()
+
+	le := binary.LittleEndian
+	var stream bytes.Buffer
+	writeRecord := func(id uint16, data []byte) {
+		binary.Write(&stream, le, id)
+		binary.Write(&stream, le, uint16(len(data)))
+		stream.Write(data)
+	}
+	bof := func(substream uint16) []byte {
+		data := make([]byte, 16)
+		le.PutUint16(data[0:], 0x0600) // BIFF8
+		le.PutUint16(data[2:], substream)
+		return data
+	}
+	xf := func(format uint16) []byte {
+		data := make([]byte, 20)
+		le.PutUint16(data[2:], format)
+		return data
+	}
+
+	// Workbook globals: number formats, XFs, the sheet and the shared strings
+	writeRecord(0x0809, bof(0x0005))
+	writeRecord(0x041E, append([]byte{164, 0, 10, 0, 0}, "dd.mm.yyyy"...))
+	writeRecord(0x00E0, xf(0))
+	writeRecord(0x00E0, xf(164))
+	writeRecord(0x00E0, xf(14))
+
+	boundSheetOffset := stream.Len() + 4
+	writeRecord(0x0085, append([]byte{0, 0, 0, 0, 0, 0, byte(len(sheet)), 0}, sheet...))
+
+	var strings []string
+	stringIndexes := make(map[string]int)
+	for _, cell := range cells {
+		if _, ok := stringIndexes[cell.Text]; cell.Text != "" && !ok {
+			stringIndexes[cell.Text] = len(strings)
+			strings = append(strings, cell.Text)
+		}
+	}
+	sst := make([]byte, 8)
+	le.PutUint32(sst[0:], uint32(len(strings)))
+	le.PutUint32(sst[4:], uint32(len(strings)))
+	for _, text := range strings {
+		sst = append(sst, byte(len(text)), byte(len(text)>>8), 0)
+		sst = append(sst, text...)
+	}
+	writeRecord(0x00FC, sst)
+	writeRecord(0x000A, nil)
+
+	// Worksheet
+	le.PutUint32(stream.Bytes()[boundSheetOffset:], uint32(stream.Len()))
+	writeRecord(0x0809, bof(0x0010))
+	for _, cell := range cells {
+		data := make([]byte, 6)
+		le.PutUint16(data[0:], uint16(cell.Row))
+		le.PutUint16(data[2:], uint16(cell.Col))
+		le.PutUint16(data[4:], uint16(cell.XF))
+		if cell.Text != "" {
+			writeRecord(0x00FD, le.AppendUint32(data, uint32(stringIndexes[cell.Text])))
+		} else {
+			writeRecord(0x0203, le.AppendUint64(data, math.Float64bits(cell.Number)))
+		}
+	}
+	writeRecord(0x000A, nil)
+
+	// Streams below 4096 bytes would be stored in the mini stream
+	const sectorSize = 512
+	workbook := stream.Bytes()
+	if len(workbook) < 4096 {
+		workbook = append(workbook, make([]byte, 4096-len(workbook))...)
+	}
+	workbookSectors := (len(workbook) + sectorSize - 1) / sectorSize
+	workbook = append(workbook, make([]byte, workbookSectors*sectorSize-len(workbook))...)
+
+	const (
+		freeSector = 0xFFFFFFFF
+		endOfChain = 0xFFFFFFFE
+		fatSector  = 0xFFFFFFFD
+	)
+
+	// Header, followed by the FAT in sector 0, the directory in sector 1 and the workbook from sector 2
+	header := make([]byte, sectorSize)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	le.PutUint16(header[24:], 0x003E)
+	le.PutUint16(header[26:], 3)      // Major version
+	le.PutUint16(header[28:], 0xFFFE) // Byte order
+	le.PutUint16(header[30:], 9)      // 512 byte sectors
+	le.PutUint16(header[32:], 6)      // 64 byte mini sectors
+	le.PutUint32(header[44:], 1)      // FAT sectors
+	le.PutUint32(header[48:], 1)      // First directory sector
+	le.PutUint32(header[56:], 4096)   // Mini stream cutoff
+	le.PutUint32(header[60:], endOfChain)
+	le.PutUint32(header[68:], endOfChain)
+	for i := 0; i < 109; i++ {
+		le.PutUint32(header[76+i*4:], freeSector)
+	}
+	le.PutUint32(header[76:], 0)
+
+	fat := make([]byte, sectorSize)
+	for i := 0; i < sectorSize/4; i++ {
+		le.PutUint32(fat[i*4:], freeSector)
+	}
+	le.PutUint32(fat[0:], fatSector)
+	le.PutUint32(fat[4:], endOfChain)
+	for i := 0; i < workbookSectors; i++ {
+		next := uint32(i + 3)
+		if i == workbookSectors-1 {
+			next = endOfChain
+		}
+		le.PutUint32(fat[(i+2)*4:], next)
+	}
+
+	directory := make([]byte, sectorSize)
+	entry := func(index int, name string, objectType byte, child uint32, start uint32, size uint32) {
+		data := directory[index*128 : (index+1)*128]
+		encoded := utf16.Encode([]rune(name + "\x00"))
+		for i, r := range encoded {
+			le.PutUint16(data[i*2:], r)
+		}
+		le.PutUint16(data[64:], uint16(len(encoded)*2))
+		data[66] = objectType
+		data[67] = 1 // Black
+		le.PutUint32(data[68:], freeSector)
+		le.PutUint32(data[72:], freeSector)
+		le.PutUint32(data[76:], child)
+		le.PutUint32(data[116:], start)
+		le.PutUint32(data[120:], size)
+	}
+	entry(0, "Root Entry", 5, 1, endOfChain, 0)
+	entry(1, "Workbook", 2, freeSector, 2, uint32(len(workbook)))
+
+	file := append(append(append(header, fat...), directory...), workbook...)
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessXLSFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Nomenclature EN.xls")
+	writeXLSFile(t, path, "Nomenclature", []xlsCell{
+		{Row: 0, Col: 0, Text: "Goods code"},
+		{Row: 0, Col: 1, Text: "Start date"},
+		{Row: 0, Col: 2, Text: "Description"},
+		{Row: 1, Col: 0, Number: 8471300000},
+		{Row: 1, Col: 1, Number: 45292, XF: 1},
+		{Row: 1, Col: 2, Text: "Portable computers"},
+		// Row 3 is empty
+		{Row: 3, Col: 0, Number: 8471410000},
+		{Row: 3, Col: 1, Number: 45658, XF: 2},
+	})
+
+	config := ParserConfig{Columns: []Column{goodsCodeColumn, startDateColumn, descriptionColumn}}
+	rowsChan := make(chan RowData)
+	go func() {
+		processXLSFile(context.Background(), inputFile{Path: path}, config, rowsChan)
+		close(rowsChan)
+	}()
+
+	var rows []ExcelRow
+	for rowData := range rowsChan {
+		rows = append(rows, rowData.(ExcelRow))
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("processXLSFile() returned %d rows, expected 2", len(rows))
+	}
+	if expected := []string{"Goods code", "Start date", "Description"}; !reflect.DeepEqual(rows[0].Headers, expected) {
+		t.Errorf("headers = %q, expected %q", rows[0].Headers, expected)
+	}
+
+	expected := []struct {
+		row         int
+		goodsCode   string
+		startDate   string
+		description string
+	}{
+		{2, "8471300000", "2024-01-01", "Portable computers"},
+		{4, "8471410000", "2025-01-01", ""},
+	}
+	for i, row := range rows {
+		if row.Source != "Nomenclature EN.xls" || row.Sheet != "Nomenclature" || row.Row != expected[i].row {
+			t.Errorf("row %d location = %q, expected %q", i, row.Location(), fmt.Sprintf("Nomenclature EN.xls, sheet \"Nomenclature\", row %d", expected[i].row))
+		}
+		if value := row.Value("Goods code"); value != expected[i].goodsCode {
+			t.Errorf("row %d goods code = %q, expected %q", i, value, expected[i].goodsCode)
+		}
+		if value := row.Value("Start date"); value != expected[i].startDate {
+			t.Errorf("row %d start date = %q, expected %q", i, value, expected[i].startDate)
+		}
+		if value := row.Value("Description"); value != expected[i].description {
+			t.Errorf("row %d description = %q, expected %q", i, value, expected[i].description)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isZipFile checks if the file has a zip archive extension
+func isZipFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// zipEntries returns the entries of a zip archive matching by name, in archive order.
+// Entries in folders of the archive are included, archives nested in it are not read.
+func zipEntries(archivePath string, match func(path string) bool) ([]inputFile, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive %s: %v", filepath.Base(archivePath), err)
+	}
+	defer archive.Close()
+
+	var files []inputFile
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || isZipMetadata(entry.Name) {
+			continue
+		}
+		if match(entry.Name) {
+			files = append(files, inputFile{Path: archivePath, Entry: entry.Name})
+		}
+	}
+
+	return files, nil
+}
+
+// isZipMetadata checks if an entry holds metadata added by the archiver, e.g. by macOS Finder
+func isZipMetadata(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
+
+// zipEntryReader streams an entry of a zip archive, closing the archive with it
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+// Close closes the entry and the archive
+func (r *zipEntryReader) Close() error {
+	r.ReadCloser.Close()
+	return r.archive.Close()
+}
+
+// openZipEntry opens an entry of a zip archive for reading
+func openZipEntry(archivePath string, name string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive %s: %v", filepath.Base(archivePath), err)
+	}
+
+	for _, entry := range archive.File {
+		if entry.Name != name {
+			continue
+		}
+
+		reader, err := entry.Open()
+		if err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("failed to open %s in zip archive %s: %v", name, filepath.Base(archivePath), err)
+		}
+
+		return &zipEntryReader{ReadCloser: reader, archive: archive}, nil
+	}
+
+	archive.Close()
+	return nil, fmt.Errorf("%s not found in zip archive %s", name, filepath.Base(archivePath))
+}
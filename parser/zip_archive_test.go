@@ -0,0 +1,69 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestZipEntries(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "bundle.zip")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := zip.NewWriter(archiveFile)
+	for _, entry := range []struct{ name, content string }{
+		{"Nomenclature EN.csv", "Goods code,Description\n0101000000 80,Horses\n"},
+		{"LT/", ""},
+		{"LT/Nomenclature LT.csv", "Goods code,Description\n0101000000 80,Arkliai\n"},
+		{"__MACOSX/._Nomenclature EN.csv", ""},
+		{"readme.txt", ""},
+	} {
+		writer, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archiveFile.Close()
+
+	files, err := zipEntries(archivePath, isCSVFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	expected := []string{"bundle.zip/Nomenclature EN.csv", "bundle.zip/LT/Nomenclature LT.csv"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("zipEntries() = %q, expected %q", names, expected)
+	}
+
+	rowsChan := make(chan RowData)
+	go func() {
+		processCSVFile(context.Background(), files[0], ParserConfig{Columns: []Column{goodsCodeColumn, descriptionColumn}}, rowsChan)
+		close(rowsChan)
+	}()
+
+	for rowData := range rowsChan {
+		row := rowData.(ExcelRow)
+		if row.Source != files[0].Name() {
+			t.Errorf("Source = %q, expected %q", row.Source, files[0].Name())
+		}
+		if row.Value("Goods code") != "0101000000 80" {
+			t.Errorf("Value(%q) = %q, expected %q", "Goods code", row.Value("Goods code"), "0101000000 80")
+		}
+	}
+}
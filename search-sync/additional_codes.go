@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/typesense/typesense-go/v3/typesense"
+	"github.com/typesense/typesense-go/v3/typesense/api"
+	"github.com/typesense/typesense-go/v3/typesense/api/pointer"
+)
+
+// loadAdditionalCodes builds search documents for the additional codes valid on the given date,
+// so declarants can search e.g. for the anti-dumping additional code of a company
+func loadAdditionalCodes(db *sql.DB, asOf time.Time) ([]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT ac.id, ac.code_type, ac.code, acd.language, acd.description
+		FROM additional_codes ac
+		JOIN additional_code_descriptions acd ON ac.id = acd.additional_code_id
+		WHERE ac.start_date <= $1 AND (ac.end_date IS NULL OR ac.end_date >= $1)
+		ORDER BY ac.id, acd.language
+	`, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query additional codes: %v", err)
+	}
+	defer rows.Close()
+
+	resultMap := make(map[int]NomenclatureResult)
+	ids := []int{}
+
+	for rows.Next() {
+		var id int
+		var codeType, code, language, description string
+		if err := rows.Scan(&id, &codeType, &code, &language, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan additional code: %v", err)
+		}
+
+		result, exists := resultMap[id]
+		if !exists {
+			isLeaf := false
+			fullCode := codeType + code
+			result = NomenclatureResult{
+				Id:                     "a" + strconv.Itoa(id),
+				GoodsCode:              fullCode,
+				GoodsCodeNumeric:       0,          // Not a goods code, kept out of numeric goods code searches
+				CategoryCodes:          []string{}, // Not part of a section or chapter
+				CategoriesEn:           []string{},
+				CategoriesLt:           []string{},
+				CategoriesLtNormalized: []string{},
+				RankBoost:              0,
+				Root:                   false,
+				IsLeaf:                 &isLeaf, // Additional codes are not part of the goods code tree
+				DocumentType:           "additional_code",
+				AdditionalCode:         fullCode,
+				AdditionalCodeType:     codeType,
+			}
+			ids = append(ids, id)
+		}
+
+		if language == "EN" {
+			result.DescriptionEn = description
+		} else if language == "LT" {
+			result.DescriptionLt = description
+			result.DescriptionLtNormalized = removeDiacritics(description)
+		}
+
+		resultMap[id] = result
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, resultMap[id])
+	}
+
+	return results, nil
+}
+
+// importAdditionalCodes imports additional code documents into the nomenclatures collection
+func importAdditionalCodes(client *typesense.Client, results []interface{}) {
+	if len(results) == 0 {
+		return
+	}
+
+	log.Printf("Importing %d additional codes to Typesense", len(results))
+
+	action := api.Create
+	params := &api.ImportDocumentsParams{
+		Action:    &action,
+		BatchSize: pointer.Int(1000),
+	}
+
+	importResult, err := client.Collection("nomenclatures").Documents().Import(context.Background(), results, params)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	successCount := 0
+	for _, doc := range importResult {
+		if doc.Success {
+			successCount++
+		} else {
+			log.Printf("Error importing additional code: %s", doc.Error)
+		}
+	}
+
+	log.Printf("Additional code import completed: %d successful, %d failed",
+		successCount, len(results)-successCount)
+}
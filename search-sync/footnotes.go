@@ -0,0 +1,34 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// loadFootnotes loads the codes of footnotes valid on the given date, keyed by the nomenclature they are associated with
+func loadFootnotes(db *sql.DB, asOf time.Time) (map[int][]string, error) {
+	rows, err := db.Query(`
+		SELECT fn.nomenclature_id, f.footnote_type || f.code
+		FROM footnote_nomenclatures fn
+		JOIN footnotes f ON fn.footnote_id = f.id
+		WHERE f.start_date <= $1 AND (f.end_date IS NULL OR f.end_date >= $1)
+		ORDER BY fn.nomenclature_id, f.footnote_type, f.code
+	`, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query footnotes: %v", err)
+	}
+	defer rows.Close()
+
+	footnotes := make(map[int][]string)
+	for rows.Next() {
+		var nomenclatureID int
+		var code string
+		if err := rows.Scan(&nomenclatureID, &code); err != nil {
+			return nil, fmt.Errorf("failed to scan footnote: %v", err)
+		}
+		footnotes[nomenclatureID] = append(footnotes[nomenclatureID], code)
+	}
+
+	return footnotes, rows.Err()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"log"
 	"muj/database"
 	"muj/utils"
@@ -11,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/joho/godotenv"
@@ -51,6 +53,10 @@ type NomenclatureResult struct {
     RankBoost      int       `json:"rank_boost"`
     Root           bool      `json:"root"`
     IsLeaf         *bool     `json:"is_leaf"`
+    DocumentType   string    `json:"document_type"` // "section", "nomenclature" or "additional_code"
+    AdditionalCode string    `json:"additional_code,omitempty"` // Set only for additional code documents, e.g. "C999"
+    AdditionalCodeType string `json:"additional_code_type,omitempty"` // Type of an additional code document, e.g. "C"
+    Footnotes      []string  `json:"footnotes,omitempty"` // Footnotes of goods code documents, e.g. "TN701"
     // CategoriesPath map[string]string   `json:"categories_path"`
 	// CategoryCodesPath string `json:"category_codes_path"`
 }
@@ -73,6 +79,15 @@ func removeDiacritics(input string) string {
 }
 
 func main () {
+	// The nomenclature valid on this date is indexed, equivalent to the TARIC SimDate parameter
+	asOfFlag := flag.String("as-of", time.Now().Format("2006-01-02"), "Date the indexed nomenclature must be valid on (YYYY-MM-DD)")
+	flag.Parse()
+
+	asOf, err := time.Parse("2006-01-02", *asOfFlag)
+	if err != nil {
+		log.Fatalf("Invalid -as-of date %q: %v", *asOfFlag, err)
+	}
+
 	// Load environment variables from the same directory as this file
 	if err := godotenv.Load(filepath.Join(utils.GetAbsolutePath(".env"))); err != nil {
 		log.Fatal("Error loading .env file")
@@ -105,9 +120,14 @@ func main () {
             JOIN section_descriptions sd ON 
                 scm.section_number = sd.section_number AND
                 nd.language = sd.language
+            WHERE ni.start_date <= $3 AND (ni.end_date IS NULL OR ni.end_date >= $3)
+                AND nd.descr_start_date = (
+                    SELECT MAX(d.descr_start_date) FROM nomenclature_descriptions d
+                    WHERE d.nomenclature_id = ni.id AND d.language = nd.language AND d.descr_start_date <= $3
+                )
             ORDER BY ni.id
             LIMIT $1 OFFSET $2
-        `, chunkSize, offset)
+        `, chunkSize, offset, asOf)
 
 		if err != nil {
             log.Fatal(err)
@@ -170,6 +190,12 @@ func main () {
         offset += chunkSize
 	}
     
+	// Load footnotes of all goods codes
+	footnotes, err := loadFootnotes(db, asOf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Create a map to store results by goodsCode
 	resultMap := make(map[string]NomenclatureResult)
 
@@ -202,6 +228,8 @@ func main () {
 						RankBoost:      0,
 						Root:           false,
 						IsLeaf:         entry.IsLeaf,
+						DocumentType:   "nomenclature",
+						Footnotes:      footnotes[entry.ID],
 					}
 				}
 
@@ -309,6 +337,7 @@ func main () {
 				CategoriesLtNormalized: []string{},
 				Root:         true,
 				IsLeaf:       &isLeaf, // Sections are not leaf nodes
+				DocumentType: "section",
 			}
 		}
 		
@@ -400,6 +429,28 @@ func main () {
             {
                 Name: "is_leaf",
                 Type: "bool",
+            },
+            {
+                Name: "document_type",
+                Type: "string",
+                Facet: pointer.True(),
+            },
+            {
+                Name: "additional_code",
+                Type: "string",
+                Optional: pointer.True(),
+            },
+            {
+                Name: "additional_code_type",
+                Type: "string",
+                Facet: pointer.True(),
+                Optional: pointer.True(),
+            },
+            {
+                Name: "footnotes",
+                Type: "string[]",
+                Facet: pointer.True(),
+                Optional: pointer.True(),
             },
 		},
 	}
@@ -438,6 +489,13 @@ func main () {
 			successCount, len(sectionResults)-successCount)
 	}
 
+	// Import additional codes as a separate document type
+	additionalCodeResults, err := loadAdditionalCodes(db, asOf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	importAdditionalCodes(client, additionalCodeResults)
+
 	chunkSize = 1000
     rowCount := 0
     totalRecords := len(results)